@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+)
+
+// CheckActive reports why user may not log in right now, if anything.
+func (u User) CheckActive(now time.Time) error {
+	if u.Disabled {
+		return fmt.Errorf("account is disabled")
+	}
+	if now.Before(u.LockedUntil) {
+		return fmt.Errorf("account is locked until %s", u.LockedUntil.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// CheckAccount reports whether username still exists and may log in. Used to
+// re-validate session tokens, which outlive account changes.
+func CheckAccount(username string) (User, error) {
+	db, err := LoadUsers()
+	if err != nil {
+		return User{}, err
+	}
+	user, exists := db.Users[username]
+	if !exists {
+		return User{}, fmt.Errorf("account no longer exists")
+	}
+	return user, user.CheckActive(time.Now())
+}
+
+// countActiveAdmins counts admins other than exclude that can still log in.
+func countActiveAdmins(db *UserDatabase, exclude string) int {
+	count := 0
+	for username, user := range db.Users {
+		if username != exclude && user.IsAdmin && !user.Disabled {
+			count++
+		}
+	}
+	return count
+}
+
+// updateAccount applies change to target on behalf of an admin, refusing
+// changes that would leave no usable admin account.
+func updateAccount(adminUsername string, isAdmin bool, target string, removesAdmin bool, change func(db *UserDatabase, user *User) error) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can manage accounts")
+	}
+	if target == adminUsername {
+		return fmt.Errorf("you cannot do that to your own account")
+	}
+
+	return UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[target]
+		if !exists {
+			return fmt.Errorf("user '%s' not found", target)
+		}
+		if removesAdmin && user.IsAdmin && countActiveAdmins(db, target) == 0 {
+			return fmt.Errorf("'%s' is the last active admin", target)
+		}
+		if err := change(db, &user); err != nil {
+			return err
+		}
+		if _, stillExists := db.Users[target]; stillExists {
+			db.Users[target] = user
+		}
+		return nil
+	})
+}
+
+// DisableUser blocks target from logging in until re-enabled.
+func DisableUser(adminUsername string, isAdmin bool, target string) error {
+	return updateAccount(adminUsername, isAdmin, target, true, func(db *UserDatabase, user *User) error {
+		user.Disabled = true
+		return nil
+	})
+}
+
+// EnableUser re-enables target and lifts any lock.
+func EnableUser(adminUsername string, isAdmin bool, target string) error {
+	return updateAccount(adminUsername, isAdmin, target, false, func(db *UserDatabase, user *User) error {
+		user.Disabled = false
+		user.LockedUntil = time.Time{}
+		user.FailedLogins = 0
+		return nil
+	})
+}
+
+// LockUser blocks target from logging in for duration.
+func LockUser(adminUsername string, isAdmin bool, target string, duration time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("lock duration must be positive")
+	}
+	return updateAccount(adminUsername, isAdmin, target, false, func(db *UserDatabase, user *User) error {
+		user.LockedUntil = time.Now().Add(duration)
+		return nil
+	})
+}
+
+// DeleteUser removes target from the user database.
+func DeleteUser(adminUsername string, isAdmin bool, target string) error {
+	return updateAccount(adminUsername, isAdmin, target, true, func(db *UserDatabase, user *User) error {
+		delete(db.Users, target)
+		return nil
+	})
+}
+
+// SetUserGroups replaces target's groups, which room access lists refer to.
+func SetUserGroups(adminUsername string, isAdmin bool, target string, groups []string) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can manage groups")
+	}
+	return UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[target]
+		if !exists {
+			return fmt.Errorf("user '%s' not found", target)
+		}
+		user.Groups = groups
+		db.Users[target] = user
+		return nil
+	})
+}
@@ -0,0 +1,572 @@
+// Package auth manages Secuchat user accounts, password hashing and
+// server-signed session tokens.
+package auth
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/vault"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+const (
+	UserDBFile = "users.json"
+	SaltSize   = 16
+	KeySize    = 32
+)
+
+// ErrAuthFailed is returned for a wrong username or password.
+var ErrAuthFailed = errors.New("authentication failed")
+
+type User struct {
+	// Verifier is the public login key derived from the password and Salt;
+	// see PasswordVerifier. It cannot be used to log in.
+	Verifier    string    `json:"verifier"`
+	Salt        string    `json:"salt"`
+	IsAdmin     bool      `json:"is_admin"`
+	DisplayName string    `json:"display_name"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+
+	// PasswordHash is the argon2 hash stored by older versions. It is
+	// password-equivalent, so LoadUsers turns it into a Verifier.
+	PasswordHash string `json:"password_hash,omitempty"`
+
+	// Groups are used by room access lists.
+	Groups []string `json:"groups,omitempty"`
+
+	// MustChangePassword is set by an admin reset; the user has to pick a
+	// new password at their next login.
+	MustChangePassword bool      `json:"must_change_password,omitempty"`
+	PasswordChangedAt  time.Time `json:"password_changed_at,omitempty"`
+
+	// Disabled accounts cannot log in until an admin re-enables them;
+	// locked accounts cannot log in until LockedUntil passes.
+	Disabled    bool      `json:"disabled,omitempty"`
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+
+	// Consecutive failed logins since the last success; see LockoutPolicy.
+	FailedLogins    int       `json:"failed_logins,omitempty"`
+	LastFailedLogin time.Time `json:"last_failed_login,omitempty"`
+
+	// TOTPSecret is the base32 RFC 6238 secret once 2FA is enrolled.
+	// TOTPLastStep is the time step of the last accepted code, so codes
+	// cannot be replayed.
+	TOTPSecret   string `json:"totp_secret,omitempty"`
+	TOTPLastStep int64  `json:"totp_last_step,omitempty"`
+}
+
+type UserDatabase struct {
+	Users map[string]User `json:"users"`
+
+	// RequireAdminTOTP makes two-factor authentication mandatory for admins.
+	RequireAdminTOTP bool `json:"require_admin_totp,omitempty"`
+}
+
+// passwordSeed is the argon2 hash of password, used as the login key seed.
+func passwordSeed(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, KeySize)
+}
+
+func GenerateSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// userDBLabel binds the sealed user database to its purpose.
+const userDBLabel = "secuchat/users"
+
+// userVault seals the user database at rest once set with UseVault.
+var userVault *vault.Vault
+
+// UseVault makes LoadUsers decrypt and SaveUsers encrypt the user database.
+func UseVault(v *vault.Vault) {
+	userVault = v
+}
+
+func LoadUsers() (UserDatabase, error) {
+	db, err := readUsers()
+	if err != nil {
+		return db, err
+	}
+	upgradeVerifiers(&db)
+	return db, nil
+}
+
+// readUsers loads the user database as stored on disk.
+func readUsers() (UserDatabase, error) {
+	var db UserDatabase
+	db.Users = make(map[string]User)
+
+	if _, err := os.Stat(UserDBFile); os.IsNotExist(err) {
+		return db, nil
+	}
+
+	data, err := os.ReadFile(UserDBFile)
+	if err != nil {
+		return db, err
+	}
+
+	if vault.IsSealed(data) {
+		if userVault == nil {
+			return db, fmt.Errorf("%s is encrypted; set %s or %s", UserDBFile, vault.PassphraseEnv, vault.KeyFileEnv)
+		}
+		data, err = userVault.Open(data, userDBLabel)
+		if err != nil {
+			return db, fmt.Errorf("cannot decrypt %s: %w", UserDBFile, err)
+		}
+	}
+
+	// Plaintext files from older versions still load; SaveUsers seals them.
+	if err := json.Unmarshal(data, &db); err != nil {
+		return db, fmt.Errorf("cannot parse %s: %w", UserDBFile, err)
+	}
+	if db.Users == nil {
+		db.Users = make(map[string]User)
+	}
+
+	return db, nil
+}
+
+// upgradeVerifiers replaces the password hashes of older databases with
+// verifiers. The old hash is the login key seed, so passwords keep working.
+func upgradeVerifiers(db *UserDatabase) bool {
+	upgraded := false
+	for username, user := range db.Users {
+		if user.PasswordHash == "" {
+			continue
+		}
+		if seed, err := base64.StdEncoding.DecodeString(user.PasswordHash); err == nil && len(seed) == KeySize && user.Verifier == "" {
+			user.Verifier = KeyVerifier(ed25519.NewKeyFromSeed(seed))
+		}
+		user.PasswordHash = ""
+		db.Users[username] = user
+		upgraded = true
+	}
+	return upgraded
+}
+
+// UpgradeUserDB rewrites a user database that still holds password hashes
+// from an older version, so only verifiers remain on disk.
+func UpgradeUserDB() (bool, error) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	db, err := readUsers()
+	if err != nil {
+		return false, err
+	}
+	if !upgradeVerifiers(&db) {
+		return false, nil
+	}
+	return true, SaveUsers(db)
+}
+
+func SaveUsers(db UserDatabase) error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	if userVault != nil {
+		data, err = userVault.Seal(data, userDBLabel)
+		if err != nil {
+			return err
+		}
+	}
+	return vault.WriteFileAtomic(UserDBFile, data, 0600)
+}
+
+// dbMu serializes read-modify-write cycles on the user database within
+// this process.
+var dbMu sync.Mutex
+
+// UpdateUsers loads the user database, applies update and saves it.
+func UpdateUsers(update func(db *UserDatabase) error) error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	db, err := LoadUsers()
+	if err != nil {
+		return err
+	}
+	if err := update(&db); err != nil {
+		return err
+	}
+	return SaveUsers(db)
+}
+
+// UserDBSealed reports whether the user database on disk is encrypted. A
+// missing database counts as sealed, since it will be written sealed.
+func UserDBSealed() (bool, error) {
+	data, err := os.ReadFile(UserDBFile)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return vault.IsSealed(data), nil
+}
+
+// EncryptUserDB migrates a plaintext user database to the sealed format.
+func EncryptUserDB() error {
+	if userVault == nil {
+		return fmt.Errorf("no database key configured")
+	}
+	db, err := LoadUsers()
+	if err != nil {
+		return err
+	}
+	return SaveUsers(db)
+}
+
+func ReadPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	bytePassword, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(bytePassword), nil
+}
+
+func InitialSetup() error {
+	db, err := LoadUsers()
+	if err != nil {
+		return err
+	}
+
+	if len(db.Users) > 0 {
+		fmt.Println("❌ Users already exist. Use admin account to create more users.")
+		return fmt.Errorf("users already exist")
+	}
+
+	fmt.Println("🚀 Secuchat-CLI Initial Setup")
+	fmt.Println("==============================")
+	fmt.Println("Creating first admin account...")
+
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Print("Admin username: ")
+	username, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	username = strings.TrimSpace(username)
+
+	if username == "" {
+		return fmt.Errorf("username cannot be empty")
+	}
+
+	password, err := PromptNewPassword("Admin password: ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Print("Display name: ")
+	displayName, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	displayName = strings.TrimSpace(displayName)
+
+	if displayName == "" {
+		displayName = username
+	}
+
+	salt, err := GenerateSalt()
+	if err != nil {
+		return err
+	}
+
+	db.Users[username] = User{
+		Verifier:    PasswordVerifier(password, salt),
+		Salt:        base64.StdEncoding.EncodeToString(salt),
+		IsAdmin:     true,
+		DisplayName: displayName,
+		CreatedAt:   time.Now(),
+		CreatedBy:   "SYSTEM",
+	}
+
+	err = SaveUsers(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Admin account '%s' created successfully!\n", username)
+	return nil
+}
+
+func CreateUser(creatorUsername string, isCreatorAdmin bool) error {
+	if !isCreatorAdmin {
+		return fmt.Errorf("only admins can create new users")
+	}
+
+	db, err := LoadUsers()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n➕ Create New User")
+	fmt.Println("===================")
+
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Print("New username: ")
+	username, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	username = strings.TrimSpace(username)
+
+	if username == "" {
+		return fmt.Errorf("username cannot be empty")
+	}
+
+	if _, exists := db.Users[username]; exists {
+		return fmt.Errorf("username '%s' already exists", username)
+	}
+
+	password, err := PromptNewPassword("Password: ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Print("Display name: ")
+	displayName, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	displayName = strings.TrimSpace(displayName)
+
+	if displayName == "" {
+		displayName = username
+	}
+
+	fmt.Print("Grant admin privileges? (y/N): ")
+	adminChoice, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	isAdmin := strings.ToLower(strings.TrimSpace(adminChoice)) == "y"
+
+	salt, err := GenerateSalt()
+	if err != nil {
+		return err
+	}
+
+	db.Users[username] = User{
+		Verifier:    PasswordVerifier(password, salt),
+		Salt:        base64.StdEncoding.EncodeToString(salt),
+		IsAdmin:     isAdmin,
+		DisplayName: displayName,
+		CreatedAt:   time.Now(),
+		CreatedBy:   creatorUsername,
+	}
+
+	err = SaveUsers(db)
+	if err != nil {
+		return err
+	}
+
+	role := "USER"
+	if isAdmin {
+		role = "ADMIN"
+	}
+
+	fmt.Printf("✅ User '%s' (%s) [%s] created successfully!\n\n", username, displayName, role)
+	return nil
+}
+
+// PromptCredentials asks for a username and a hidden password.
+func PromptCredentials() (string, string, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Print("Username: ")
+	username, err := reader.ReadString('\n')
+	if err != nil {
+		return "", "", err
+	}
+	username = strings.TrimSpace(username)
+
+	password, err := ReadPassword("Password: ")
+	if err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// AuthenticateUser checks a username and password against the local user database.
+func AuthenticateUser(username, password string) (User, error) {
+	db, err := LoadUsers()
+	if err != nil {
+		return User{}, err
+	}
+
+	user, exists := db.Users[username]
+	if !exists {
+		return User{}, ErrAuthFailed
+	}
+
+	if err := user.CheckNotLocked(time.Now()); err != nil {
+		return User{}, err
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(user.Salt)
+	if err != nil {
+		return User{}, err
+	}
+
+	verifier := PasswordVerifier(password, salt)
+	if subtle.ConstantTimeCompare([]byte(verifier), []byte(user.Verifier)) != 1 {
+		if _, err := RecordLoginFailure(username); err != nil {
+			return User{}, err
+		}
+		return User{}, ErrAuthFailed
+	}
+
+	// With 2FA the login only succeeds once the code is checked too
+	if !db.NeedsTOTP(user) {
+		if err := RecordLoginSuccess(username); err != nil {
+			return User{}, err
+		}
+	}
+
+	if err := user.CheckActive(time.Now()); err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func Login() (string, bool, error) {
+	db, err := LoadUsers()
+	if err != nil {
+		return "", false, err
+	}
+
+	if len(db.Users) == 0 {
+		fmt.Println("⚠️  No users found. Please run initial setup first:")
+		fmt.Println("   secuchat-server --setup")
+		return "", false, fmt.Errorf("no users configured")
+	}
+
+	fmt.Println("🔒 Secuchat-CLI Authentication")
+	fmt.Println("===============================")
+
+	username, password, err := PromptCredentials()
+	if err != nil {
+		return "", false, err
+	}
+
+	user, err := AuthenticateUser(username, password)
+	if err != nil {
+		if errors.Is(err, ErrAuthFailed) {
+			fmt.Println("❌ Invalid username or password")
+		}
+		return "", false, err
+	}
+
+	if err := checkSecondFactor(username); err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			fmt.Println("❌ Invalid verification code")
+		}
+		return "", false, err
+	}
+
+	if user.MustChangePassword {
+		if err := forcePasswordChange(username, password); err != nil {
+			return "", false, err
+		}
+	}
+
+	role := "USER"
+	if user.IsAdmin {
+		role = "ADMIN"
+	}
+
+	fmt.Printf("✅ Authentication successful! Welcome, %s [%s]\n", user.DisplayName, role)
+	return username, user.IsAdmin, nil
+}
+
+func ListUsers() error {
+	db, err := LoadUsers()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n👥 Registered Users")
+	fmt.Println("====================")
+	if db.RequireAdminTOTP {
+		fmt.Println("🔐 Two-factor authentication is required for admins.")
+	}
+
+	if len(db.Users) == 0 {
+		fmt.Println("No users found.")
+		return nil
+	}
+
+	fmt.Println("\nADMIN ACCOUNTS:")
+	hasAdmins := false
+	for username, user := range db.Users {
+		if user.IsAdmin {
+			hasAdmins = true
+			fmt.Printf("  • %s (%s) - Created: %s by %s%s\n",
+				username, user.DisplayName,
+				user.CreatedAt.Format("2006-01-02"), user.CreatedBy, accountStatus(user))
+		}
+	}
+	if !hasAdmins {
+		fmt.Println("  (none)")
+	}
+
+	fmt.Println("\nUSER ACCOUNTS:")
+	hasUsers := false
+	for username, user := range db.Users {
+		if !user.IsAdmin {
+			hasUsers = true
+			fmt.Printf("  • %s (%s) - Created: %s by %s%s\n",
+				username, user.DisplayName,
+				user.CreatedAt.Format("2006-01-02"), user.CreatedBy, accountStatus(user))
+		}
+	}
+	if !hasUsers {
+		fmt.Println("  (none)")
+	}
+	fmt.Println()
+
+	return nil
+}
+
+// accountStatus is the ListUsers suffix describing an account's state.
+func accountStatus(user User) string {
+	if user.Disabled {
+		return " [DISABLED]"
+	}
+	if time.Now().Before(user.LockedUntil) {
+		return " [LOCKED until " + user.LockedUntil.Local().Format("2006-01-02 15:04") + "]"
+	}
+	status := ""
+	if user.TOTPSecret != "" {
+		status = " [2FA]"
+	}
+	if len(user.Groups) > 0 {
+		status += " groups: " + strings.Join(user.Groups, ", ")
+	}
+	return status
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+)
+
+const NonceSize = 32
+
+// proofContext separates login signatures from any other use of the key.
+const proofContext = "secuchat/login\x00"
+
+// NewNonce returns a fresh random login challenge.
+func NewNonce() ([]byte, error) {
+	nonce := make([]byte, NonceSize)
+	_, err := rand.Read(nonce)
+	return nonce, err
+}
+
+// LoginKey derives the signing key a client logs in with. Its seed is the
+// argon2 hash of the password, so only someone who knows the password can
+// rebuild it.
+func LoginKey(password string, salt []byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(passwordSeed(password, salt))
+}
+
+// PasswordVerifier returns the base64 public half of the login key. It is
+// all the server stores: it checks proofs but cannot produce them.
+func PasswordVerifier(password string, salt []byte) string {
+	return KeyVerifier(LoginKey(password, salt))
+}
+
+// KeyVerifier returns the base64 public half of a login key.
+func KeyVerifier(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+func proofMessage(nonce []byte, username string) []byte {
+	message := make([]byte, 0, len(proofContext)+len(nonce)+len(username))
+	message = append(message, proofContext...)
+	message = append(message, nonce...)
+	return append(message, username...)
+}
+
+// ComputeProof signs a single server nonce and the username with the login
+// key, so neither the password nor anything that can replace it crosses the
+// wire.
+func ComputeProof(key ed25519.PrivateKey, nonce []byte, username string) []byte {
+	return ed25519.Sign(key, proofMessage(nonce, username))
+}
+
+// VerifyProof checks a client proof against the stored verifier.
+func VerifyProof(verifier, nonce []byte, username string, proof []byte) bool {
+	if len(verifier) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(verifier), proofMessage(nonce, username), proof)
+}
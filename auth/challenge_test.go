@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestVerifyProof(t *testing.T) {
+	salt := bytes.Repeat([]byte{3}, SaltSize)
+	nonce := bytes.Repeat([]byte{4}, NonceSize)
+	verifier, err := base64.StdEncoding.DecodeString(PasswordVerifier("password1", salt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := ComputeProof(LoginKey("password1", salt), nonce, "alice")
+
+	tests := []struct {
+		name     string
+		verifier []byte
+		nonce    []byte
+		username string
+		proof    []byte
+		want     bool
+	}{
+		{"valid", verifier, nonce, "alice", proof, true},
+		{"wrong password", verifier, nonce, "alice", ComputeProof(LoginKey("password2", salt), nonce, "alice"), false},
+		{"other nonce", verifier, bytes.Repeat([]byte{5}, NonceSize), "alice", proof, false},
+		{"other user", verifier, nonce, "bob", proof, false},
+		{"truncated proof", verifier, nonce, "alice", proof[:len(proof)-1], false},
+		{"bad verifier", verifier[:16], nonce, "alice", proof, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyProof(tt.verifier, tt.nonce, tt.username, tt.proof); got != tt.want {
+				t.Fatalf("VerifyProof() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
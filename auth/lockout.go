@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LockoutPolicy decides how long logins are blocked after repeated failures.
+// Once MaxFailures consecutive failures are reached the lock starts at
+// BaseLockout and doubles with every further failure, up to MaxLockout.
+type LockoutPolicy struct {
+	MaxFailures int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+var lockoutPolicy = LockoutPolicy{
+	MaxFailures: 5,
+	BaseLockout: time.Minute,
+	MaxLockout:  24 * time.Hour,
+}
+
+// SetLockoutPolicy replaces the default policy. A MaxFailures of zero or
+// less disables automatic lockout.
+func SetLockoutPolicy(p LockoutPolicy) {
+	lockoutPolicy = p
+}
+
+// CurrentLockoutPolicy returns the policy in effect.
+func CurrentLockoutPolicy() LockoutPolicy {
+	return lockoutPolicy
+}
+
+// LockDuration returns how long to lock after failures consecutive failed
+// attempts, or zero when no lock is due.
+func (p LockoutPolicy) LockDuration(failures int) time.Duration {
+	if p.MaxFailures <= 0 || failures < p.MaxFailures {
+		return 0
+	}
+	lock := p.BaseLockout
+	for i := p.MaxFailures; i < failures && lock < p.MaxLockout; i++ {
+		lock *= 2
+	}
+	if lock > p.MaxLockout {
+		lock = p.MaxLockout
+	}
+	return lock
+}
+
+// RecordLoginFailure counts a failed login for username and locks the
+// account when the policy says so. Unknown users are ignored.
+func RecordLoginFailure(username string) (time.Time, error) {
+	var lockedUntil time.Time
+	err := UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[username]
+		if !exists {
+			return nil
+		}
+		now := time.Now()
+		user.FailedLogins++
+		user.LastFailedLogin = now
+		if lock := lockoutPolicy.LockDuration(user.FailedLogins); lock > 0 {
+			user.LockedUntil = now.Add(lock)
+		}
+		lockedUntil = user.LockedUntil
+		db.Users[username] = user
+		return nil
+	})
+	return lockedUntil, err
+}
+
+// RecordLoginSuccess clears the failure count for username.
+func RecordLoginSuccess(username string) error {
+	db, err := LoadUsers()
+	if err != nil {
+		return err
+	}
+	if db.Users[username].FailedLogins == 0 {
+		return nil
+	}
+
+	return UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[username]
+		if !exists {
+			return nil
+		}
+		user.FailedLogins = 0
+		db.Users[username] = user
+		return nil
+	})
+}
+
+// CheckNotLocked reports a temporary lock without revealing anything else
+// about the account. It is checked before the password is evaluated, so
+// guesses against a locked account are not even tried.
+func (u User) CheckNotLocked(now time.Time) error {
+	if now.Before(u.LockedUntil) {
+		return fmt.Errorf("too many failed attempts; account locked until %s", u.LockedUntil.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// Lockout describes an account with a lock or failed attempts on record.
+type Lockout struct {
+	Username        string
+	FailedLogins    int
+	LastFailedLogin time.Time
+	LockedUntil     time.Time
+}
+
+// ListLockouts returns accounts that are locked or have recent failures.
+func ListLockouts() ([]Lockout, error) {
+	db, err := LoadUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var lockouts []Lockout
+	for username, user := range db.Users {
+		if user.FailedLogins > 0 || now.Before(user.LockedUntil) {
+			lockouts = append(lockouts, Lockout{
+				Username:        username,
+				FailedLogins:    user.FailedLogins,
+				LastFailedLogin: user.LastFailedLogin,
+				LockedUntil:     user.LockedUntil,
+			})
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].Username < lockouts[j].Username })
+	return lockouts, nil
+}
+
+// UnlockUser lifts a lock on target and clears its failure count.
+func UnlockUser(adminUsername string, isAdmin bool, target string) error {
+	return updateAccount(adminUsername, isAdmin, target, false, func(db *UserDatabase, user *User) error {
+		user.LockedUntil = time.Time{}
+		user.FailedLogins = 0
+		return nil
+	})
+}
+
+// PrintLockouts lists locked accounts and failure counts for the CLI.
+func PrintLockouts() error {
+	lockouts, err := ListLockouts()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🔒 Failed Logins and Lockouts")
+	fmt.Println("==============================")
+	if len(lockouts) == 0 {
+		fmt.Println("No failed logins on record.")
+		return nil
+	}
+
+	now := time.Now()
+	for _, l := range lockouts {
+		status := ""
+		if now.Before(l.LockedUntil) {
+			status = " [LOCKED until " + l.LockedUntil.Local().Format("2006-01-02 15:04") + "]"
+		}
+		fmt.Printf("  • %s - %d failed, last %s%s\n",
+			l.Username, l.FailedLogins, l.LastFailedLogin.Local().Format("2006-01-02 15:04"), status)
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockDuration(t *testing.T) {
+	policy := LockoutPolicy{MaxFailures: 3, BaseLockout: time.Minute, MaxLockout: 10 * time.Minute}
+
+	tests := []struct {
+		name     string
+		policy   LockoutPolicy
+		failures int
+		want     time.Duration
+	}{
+		{"no failures", policy, 0, 0},
+		{"below limit", policy, 2, 0},
+		{"at limit", policy, 3, time.Minute},
+		{"doubles", policy, 4, 2 * time.Minute},
+		{"doubles again", policy, 6, 8 * time.Minute},
+		{"capped", policy, 7, 10 * time.Minute},
+		{"stays capped", policy, 1000, 10 * time.Minute},
+		{"disabled", LockoutPolicy{BaseLockout: time.Minute, MaxLockout: time.Hour}, 1000, 0},
+		{"base above max", LockoutPolicy{MaxFailures: 1, BaseLockout: time.Hour, MaxLockout: time.Minute}, 1, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.LockDuration(tt.failures); got != tt.want {
+				t.Fatalf("LockDuration(%d) = %s, want %s", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
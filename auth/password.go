@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MinPasswordLength is enforced wherever a password is typed. Passwords
+// changed over the login handshake never reach the server, so only the
+// client can check them.
+const MinPasswordLength = 8
+
+// PromptNewPassword asks for a new password twice and checks it is usable.
+func PromptNewPassword(prompt string) (string, error) {
+	password, err := ReadPassword(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	confirmPassword, err := ReadPassword("Confirm password: ")
+	if err != nil {
+		return "", err
+	}
+
+	if password != confirmPassword {
+		return "", fmt.Errorf("passwords do not match")
+	}
+
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+
+	return password, nil
+}
+
+// SetPassword stores a fresh salt and the verifier for password on user and
+// clears any pending forced change.
+func (u *User) SetPassword(password string) error {
+	salt, err := GenerateSalt()
+	if err != nil {
+		return err
+	}
+	return u.SetVerifier(base64.StdEncoding.EncodeToString(salt), PasswordVerifier(password, salt))
+}
+
+// SetVerifier stores a salt and verifier computed elsewhere (by a client
+// changing its password over the login handshake). Both are base64.
+func (u *User) SetVerifier(salt, verifier string) error {
+	rawSalt, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil || len(rawSalt) != SaltSize {
+		return fmt.Errorf("invalid password salt")
+	}
+	rawVerifier, err := base64.StdEncoding.DecodeString(verifier)
+	if err != nil || len(rawVerifier) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid password verifier")
+	}
+
+	u.Salt = salt
+	u.Verifier = verifier
+	u.PasswordHash = ""
+	u.MustChangePassword = false
+	u.PasswordChangedAt = time.Now()
+	return nil
+}
+
+// ChangePassword re-authenticates a user and sets a new password.
+func ChangePassword() error {
+	fmt.Println("🔑 Change Password")
+	fmt.Println("==================")
+
+	username, password, err := PromptCredentials()
+	if err != nil {
+		return err
+	}
+
+	current, err := AuthenticateUser(username, password)
+	if err != nil {
+		if errors.Is(err, ErrAuthFailed) {
+			fmt.Println("❌ Invalid username or password")
+		}
+		return err
+	}
+	if err := checkSecondFactor(username); err != nil {
+		return err
+	}
+
+	newPassword, err := PromptNewPassword("New password: ")
+	if err != nil {
+		return err
+	}
+	if newPassword == password {
+		return fmt.Errorf("new password must differ from the current one")
+	}
+
+	err = UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[username]
+		if !exists || user.Verifier != current.Verifier {
+			return fmt.Errorf("account changed during update; try again")
+		}
+		if err := user.SetPassword(newPassword); err != nil {
+			return err
+		}
+		db.Users[username] = user
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Password for '%s' changed.\n", username)
+	return nil
+}
+
+// generateTemporaryPassword returns a random password that is easy to read
+// out over a side channel.
+func generateTemporaryPassword() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw), nil
+}
+
+// ResetPassword sets a temporary password for target and forces a change at
+// its next login. It returns the temporary password for the admin to hand over.
+func ResetPassword(adminUsername string, isAdmin bool, target string) (string, error) {
+	if !isAdmin {
+		return "", fmt.Errorf("only admins can reset passwords")
+	}
+
+	temporary, err := generateTemporaryPassword()
+	if err != nil {
+		return "", err
+	}
+
+	err = UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[target]
+		if !exists {
+			return fmt.Errorf("user '%s' not found", target)
+		}
+		if err := user.SetPassword(temporary); err != nil {
+			return err
+		}
+		user.MustChangePassword = true
+		db.Users[target] = user
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("🔄 Password for '%s' reset by %s.\n", target, adminUsername)
+	return temporary, nil
+}
+
+// forcePasswordChange prompts for a new password when an admin reset is
+// pending on username.
+func forcePasswordChange(username, oldPassword string) error {
+	fmt.Println("⚠️  Your password was reset by an admin. Choose a new password.")
+
+	newPassword, err := PromptNewPassword("New password: ")
+	if err != nil {
+		return err
+	}
+	if newPassword == oldPassword {
+		return fmt.Errorf("new password must differ from the temporary one")
+	}
+
+	return UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[username]
+		if !exists {
+			return fmt.Errorf("user '%s' not found", username)
+		}
+		if err := user.SetPassword(newPassword); err != nil {
+			return err
+		}
+		db.Users[username] = user
+		return nil
+	})
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	SessionKeyEnv = "SECUCHAT_SESSION_KEY"
+	SessionTTL    = 12 * time.Hour
+)
+
+// Session is the identity the server vouches for in a signed token.
+type Session struct {
+	Username  string    `json:"user"`
+	IsAdmin   bool      `json:"admin"`
+	IssuedAt  time.Time `json:"iat"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// SessionManager issues and verifies HMAC-SHA256 signed session tokens.
+// Tokens are "<base64url(json)>.<base64url(mac)>".
+type SessionManager struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewSessionManager uses the base64 key in SECUCHAT_SESSION_KEY when set,
+// otherwise a random key that only lives as long as the process.
+func NewSessionManager() (*SessionManager, error) {
+	var key []byte
+	if encoded := os.Getenv(SessionKeyEnv); encoded != "" {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", SessionKeyEnv, err)
+		}
+		if len(decoded) < KeySize {
+			return nil, fmt.Errorf("%s must be at least %d bytes", SessionKeyEnv, KeySize)
+		}
+		key = decoded
+	} else {
+		key = make([]byte, KeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &SessionManager{key: key, ttl: SessionTTL}, nil
+}
+
+func (m *SessionManager) sign(payload string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// DecoySalt derives a per-username salt for accounts that do not exist.
+func (m *SessionManager) DecoySalt(username string) []byte {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte("decoy-salt:" + username))
+	return mac.Sum(nil)[:SaltSize]
+}
+
+func (m *SessionManager) Issue(username string, isAdmin bool) (string, Session, error) {
+	now := time.Now().UTC()
+	session := Session{
+		Username:  username,
+		IsAdmin:   isAdmin,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(m.ttl),
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", Session{}, err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + m.sign(payload), session, nil
+}
+
+func (m *SessionManager) Verify(token string) (Session, error) {
+	var session Session
+
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || payload == "" || sig == "" {
+		return session, fmt.Errorf("malformed session token")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(m.sign(payload))) {
+		return session, fmt.Errorf("invalid session signature")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return session, fmt.Errorf("malformed session token")
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return session, fmt.Errorf("malformed session token")
+	}
+
+	if session.Username == "" {
+		return session, fmt.Errorf("session has no user")
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return session, fmt.Errorf("session expired")
+	}
+
+	return session, nil
+}
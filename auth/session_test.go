@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionVerify(t *testing.T) {
+	manager := &SessionManager{key: bytes.Repeat([]byte{1}, KeySize), ttl: time.Hour}
+	expired := &SessionManager{key: manager.key, ttl: -time.Minute}
+	other := &SessionManager{key: bytes.Repeat([]byte{2}, KeySize), ttl: time.Hour}
+
+	valid, _, err := manager.Issue("alice", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, _, err := expired.Issue("alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _, err := other.Issue("alice", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(valid, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"user":"mallory","admin":true,"exp":"2099-01-01T00:00:00Z"}`))
+	noUser := base64.RawURLEncoding.EncodeToString([]byte(`{"exp":"2099-01-01T00:00:00Z"}`))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"expired", stale, "session expired"},
+		{"other key", foreign, "invalid session signature"},
+		{"tampered payload", forged + "." + sig, "invalid session signature"},
+		{"tampered signature", payload + "." + sig[:len(sig)-2] + "AA", "invalid session signature"},
+		{"no signature", payload, "malformed session token"},
+		{"empty", "", "malformed session token"},
+		{"no user", noUser + "." + manager.sign(noUser), "session has no user"},
+		{"bad payload", "!!!." + manager.sign("!!!"), "malformed session token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := manager.Verify(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				if session.Username != "alice" || !session.IsAdmin {
+					t.Fatalf("Verify() = %+v, want alice as admin", session)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
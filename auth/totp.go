@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// RFC 6238 parameters. These are the defaults every authenticator app
+// understands, so they are not configurable.
+const (
+	TOTPIssuer     = "Secuchat"
+	TOTPDigits     = 6
+	TOTPPeriod     = 30 * time.Second
+	totpSecretSize = 20
+	// totpSkew accepts codes from one period either side of now to allow
+	// for clock drift and typing time.
+	totpSkew = 1
+)
+
+// ErrInvalidCode is returned for a wrong or already used verification code.
+var ErrInvalidCode = errors.New("invalid verification code")
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random base32 TOTP secret.
+func GenerateTOTPSecret() (string, error) {
+	raw := make([]byte, totpSecretSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(raw), nil
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// totpCodeAt computes the HOTP value (RFC 4226) for one time step.
+func totpCodeAt(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod)
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret")
+	}
+	return key, nil
+}
+
+// TOTPCode returns the code for secret at time t.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpCodeAt(key, totpStep(t)), nil
+}
+
+// matchTOTP checks code against secret around now and returns the matching
+// time step. Steps at or before lastStep are refused so a code cannot be
+// replayed.
+func matchTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCodeAt(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI returns the otpauth:// URI that authenticator apps import.
+func TOTPURI(username, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", TOTPIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+
+	label := url.PathEscape(TOTPIssuer + ":" + username)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// NeedsTOTP reports whether user must present a verification code to log in.
+func (db UserDatabase) NeedsTOTP(user User) bool {
+	return user.TOTPSecret != "" || (user.IsAdmin && db.RequireAdminTOTP)
+}
+
+// VerifyUserTOTP checks a verification code for username. A wrong code
+// counts as a failed login; a right one completes the login and clears the
+// failure count.
+func VerifyUserTOTP(username, code string) error {
+	err := UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[username]
+		if !exists || user.TOTPSecret == "" {
+			return ErrInvalidCode
+		}
+		step, ok := matchTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return ErrInvalidCode
+		}
+		user.TOTPLastStep = step
+		user.FailedLogins = 0
+		db.Users[username] = user
+		return nil
+	})
+	if errors.Is(err, ErrInvalidCode) {
+		if _, recordErr := RecordLoginFailure(username); recordErr != nil {
+			return recordErr
+		}
+	}
+	return err
+}
+
+// EnrollTOTP stores secret for username once code proves the user's
+// authenticator has it.
+func EnrollTOTP(username, secret, code string) error {
+	step, ok := matchTOTP(secret, code, time.Now(), 0)
+	if !ok {
+		return ErrInvalidCode
+	}
+	return UpdateUsers(func(db *UserDatabase) error {
+		user, exists := db.Users[username]
+		if !exists {
+			return fmt.Errorf("user '%s' not found", username)
+		}
+		user.TOTPSecret = secret
+		user.TOTPLastStep = step
+		user.FailedLogins = 0
+		db.Users[username] = user
+		return nil
+	})
+}
+
+// ResetTOTP removes target's TOTP secret, e.g. after a lost device. If 2FA
+// is required for the account it has to enroll again at the next login.
+func ResetTOTP(adminUsername string, isAdmin bool, target string) error {
+	return updateAccount(adminUsername, isAdmin, target, false, func(db *UserDatabase, user *User) error {
+		if user.TOTPSecret == "" {
+			return fmt.Errorf("'%s' has no two-factor authentication enrolled", target)
+		}
+		user.TOTPSecret = ""
+		user.TOTPLastStep = 0
+		return nil
+	})
+}
+
+// SetRequireAdminTOTP turns mandatory 2FA for admin accounts on or off.
+func SetRequireAdminTOTP(adminUsername string, isAdmin bool, require bool) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can change the 2FA policy")
+	}
+	return UpdateUsers(func(db *UserDatabase) error {
+		db.RequireAdminTOTP = require
+		return nil
+	})
+}
+
+// PromptTOTPCode asks for a verification code from the user's authenticator.
+func PromptTOTPCode(prompt string) (string, error) {
+	fmt.Print(prompt)
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(code), nil
+}
+
+// PrintTOTPEnrollment shows the secret for username in the forms
+// authenticator apps accept.
+func PrintTOTPEnrollment(username, secret string) {
+	fmt.Println("📱 Add this account to your authenticator app:")
+	fmt.Printf("   %s\n", TOTPURI(username, secret))
+	fmt.Printf("   Secret (manual entry): %s\n", secret)
+	fmt.Println("   Tip: `qrencode -t ansiutf8 '<uri>'` renders the URI as a QR code.")
+}
+
+// EnrollTOTPInteractive runs the CLI enrollment for username: it shows a new
+// secret and stores it once the user types a valid code.
+func EnrollTOTPInteractive(username string) error {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🔐 Two-Factor Enrollment")
+	fmt.Println("========================")
+	PrintTOTPEnrollment(username, secret)
+
+	code, err := PromptTOTPCode("Verification code: ")
+	if err != nil {
+		return err
+	}
+	if err := EnrollTOTP(username, secret, code); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Two-factor authentication enabled for '%s'.\n", username)
+	return nil
+}
+
+// checkSecondFactor runs the CLI second login step for username: a code
+// prompt if enrolled, or enrollment if 2FA is required but not set up yet.
+func checkSecondFactor(username string) error {
+	db, err := LoadUsers()
+	if err != nil {
+		return err
+	}
+	user := db.Users[username]
+	if !db.NeedsTOTP(user) {
+		return nil
+	}
+
+	if user.TOTPSecret == "" {
+		fmt.Println("⚠️  Two-factor authentication is required for admin accounts.")
+		return EnrollTOTPInteractive(username)
+	}
+
+	code, err := PromptTOTPCode("Verification code: ")
+	if err != nil {
+		return err
+	}
+	return VerifyUserTOTP(username, code)
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Key is the SHA-1 seed from RFC 6238 Appendix B.
+var rfc6238Key = []byte("12345678901234567890")
+
+func TestTOTPCodeAt(t *testing.T) {
+	// RFC 6238 Appendix B lists 8-digit codes; 6-digit codes are their
+	// last six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			want := tt.want[len(tt.want)-TOTPDigits:]
+			if got := totpCodeAt(rfc6238Key, totpStep(time.Unix(tt.unix, 0))); got != want {
+				t.Fatalf("totpCodeAt(T=%d) = %s, want %s", tt.unix, got, want)
+			}
+		})
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfc6238Key)
+	now := time.Unix(1234567890, 0)
+	step := totpStep(now)
+	code := func(s int64) string { return totpCodeAt(rfc6238Key, s) }
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code(step), 0, step, true},
+		{"with spaces", " " + code(step)[:3] + " " + code(step)[3:] + " ", 0, step, true},
+		{"previous step", code(step - 1), 0, step - 1, true},
+		{"next step", code(step + 1), 0, step + 1, true},
+		{"outside skew before", code(step - 2), 0, 0, false},
+		{"outside skew after", code(step + 2), 0, 0, false},
+		{"replayed", code(step), step, 0, false},
+		{"older than last used", code(step - 1), step, 0, false},
+		{"after last used", code(step + 1), step, step + 1, true},
+		{"wrong code", "000000", 0, 0, false},
+		{"too short", code(step)[:5], 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := matchTOTP(secret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Fatalf("matchTOTP(%q, last %d) = %d, %v; want %d, %v", tt.code, tt.lastStep, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+
+	if _, ok := matchTOTP("not base32!", code(step), now, 0); ok {
+		t.Fatal("matchTOTP accepted an invalid secret")
+	}
+}
@@ -0,0 +1,469 @@
+// Command secuchat-server hosts Secuchat rooms and the user database that
+// clients authenticate against.
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/history"
+	"github.com/EJ-Edwards/Secuchat-CLI/hub"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+	"github.com/EJ-Edwards/Secuchat-CLI/tlsutil"
+	"github.com/EJ-Edwards/Secuchat-CLI/vault"
+)
+
+func usage() {
+	fmt.Println("Secuchat-CLI Server")
+	fmt.Println("===================")
+	fmt.Println("Usage:")
+	fmt.Println("  secuchat-server [options]       - Start the chat server")
+	fmt.Println("  secuchat-server --setup         - Initial admin setup")
+	fmt.Println("  secuchat-server --create-user   - Create new user (admin only)")
+	fmt.Println("  secuchat-server --list-users    - List all users")
+	fmt.Println("  secuchat-server --passwd        - Change your own password")
+	fmt.Println("  secuchat-server --reset-password <user> - Set a temporary password (admin only)")
+	fmt.Println("  secuchat-server --disable-user <user>   - Block a user from logging in (admin only)")
+	fmt.Println("  secuchat-server --enable-user <user>    - Re-enable a disabled or locked user (admin only)")
+	fmt.Println("  secuchat-server --lock-user <user> --lock-for 12h - Lock a user temporarily (admin only)")
+	fmt.Println("  secuchat-server --delete-user <user>    - Delete a user (admin only)")
+	fmt.Println("  secuchat-server --enroll-2fa            - Set up TOTP two-factor login for your account")
+	fmt.Println("  secuchat-server --reset-2fa <user>      - Remove a user's TOTP enrollment (admin only)")
+	fmt.Println("  secuchat-server --require-admin-2fa on|off - Require 2FA for all admins (admin only)")
+	fmt.Println("  secuchat-server --list-lockouts         - Show failed logins and locked accounts")
+	fmt.Println("  secuchat-server --unlock-user <user>    - Clear a user's failed logins and lock (admin only)")
+	fmt.Println("  secuchat-server --set-groups <user> --groups red,ops - Set a user's groups (admin only)")
+	fmt.Println("  secuchat-server --create-room <name> [--allow-users a,b] [--allow-groups g] - Define a room (admin only)")
+	fmt.Println("  secuchat-server --room-access <name> [--allow-users a,b] [--allow-groups g] - Replace a room's access list (admin only)")
+	fmt.Println("  secuchat-server --room-ttl <name> --ttl 5m - Make a room's messages self-destruct (admin only)")
+	fmt.Println("  secuchat-server --room-topic <name> --topic \"text\" - Set or clear a room's topic (admin only)")
+	fmt.Println("  secuchat-server --room-motd <name> --motd-file motd.txt - Set or clear a room's message of the day (admin only)")
+	fmt.Println("  secuchat-server --room-file-cap <name> --file-cap 5242880 - Cap a room's file transfers in bytes (admin only)")
+	fmt.Println("  secuchat-server --ban-user <user> [--ban-room <name>] [--ban-for 24h] [--ban-reason text] - Ban a user from a room or every room (admin only)")
+	fmt.Println("  secuchat-server --unban-user <user> [--ban-room <name>] - Lift a room or global ban (admin only)")
+	fmt.Println("  secuchat-server --list-bans             - List bans in force")
+	fmt.Println("  secuchat-server --delete-room <name>    - Remove a room definition (admin only)")
+	fmt.Println("  secuchat-server --list-rooms            - List defined rooms")
+	fmt.Println("  secuchat-server --encrypt-db    - Encrypt a plaintext users.json")
+	fmt.Println("  secuchat-server --gen-cert      - Generate a self-signed TLS certificate")
+	fmt.Println("")
+	fmt.Println("Options:")
+	flag.CommandLine.SetOutput(os.Stdout)
+	flag.PrintDefaults()
+	fmt.Println("")
+	fmt.Println("Examples:")
+	fmt.Println("  secuchat-server --gen-cert --hosts 10.10.0.5,chat.op.local")
+	fmt.Println("  secuchat-server --addr 0.0.0.0:8443 --tls-cert secuchat.crt --tls-key secuchat.key")
+}
+
+func main() {
+	setup := flag.Bool("setup", false, "create the first admin account")
+	createUser := flag.Bool("create-user", false, "create a new user (admin login required)")
+	listUsers := flag.Bool("list-users", false, "list all users")
+	passwd := flag.Bool("passwd", false, "change your own password (re-authenticates first)")
+	resetPassword := flag.String("reset-password", "", "reset `user`'s password to a temporary one and force a change at next login (admin login required)")
+	disableUser := flag.String("disable-user", "", "disable `user`'s account (admin login required)")
+	enableUser := flag.String("enable-user", "", "re-enable `user`'s account and lift any lock (admin login required)")
+	lockUser := flag.String("lock-user", "", "lock `user`'s account for --lock-for (admin login required)")
+	lockFor := flag.Duration("lock-for", 24*time.Hour, "lock duration for --lock-user")
+	deleteUser := flag.String("delete-user", "", "delete `user`'s account (admin login required)")
+	enroll2FA := flag.Bool("enroll-2fa", false, "enroll your account in TOTP two-factor authentication")
+	reset2FA := flag.String("reset-2fa", "", "remove `user`'s TOTP enrollment, e.g. after a lost device (admin login required)")
+	requireAdmin2FA := flag.String("require-admin-2fa", "", "`on` or off: require two-factor authentication for every admin account (admin login required)")
+	listLockouts := flag.Bool("list-lockouts", false, "list accounts with failed logins or an active lock")
+	unlockUser := flag.String("unlock-user", "", "clear `user`'s failed logins and lock (admin login required)")
+	maxFailures := flag.Int("max-login-failures", 5, "consecutive failed logins before an account or address is locked (0 disables)")
+	lockoutBase := flag.Duration("lockout-base", time.Minute, "first lockout duration; doubles with each further failure")
+	lockoutMax := flag.Duration("lockout-max", 24*time.Hour, "longest automatic lockout")
+	setGroups := flag.String("set-groups", "", "replace `user`'s groups with --groups (admin login required)")
+	groups := flag.String("groups", "", "comma-separated groups for --set-groups")
+	createRoom := flag.String("create-room", "", "define a room called `name`; prompts for its PIN (admin login required)")
+	roomAccess := flag.String("room-access", "", "replace the access list of room `name` (admin login required)")
+	roomTTL := flag.String("room-ttl", "", "set the self-destruct timer of room `name` to --ttl (admin login required)")
+	ttl := flag.String("ttl", "off", "timer for --room-ttl: seconds, a duration like 5m, or off")
+	roomTopic := flag.String("room-topic", "", "set the topic of room `name` to --topic (admin login required)")
+	topic := flag.String("topic", "", "topic for --room-topic; empty clears it")
+	roomMOTD := flag.String("room-motd", "", "set the message of the day of room `name` from --motd-file (admin login required)")
+	motdFile := flag.String("motd-file", "", "text file for --room-motd; empty clears the message of the day")
+	roomFileCap := flag.String("room-file-cap", "", "set the file transfer cap of room `name` to --file-cap (admin login required)")
+	fileCapBytes := flag.Int64("file-cap", 0, "bytes for --room-file-cap: 0 uses --max-file-size, -1 disables transfers in the room")
+	deleteRoom := flag.String("delete-room", "", "remove the definition of room `name` (admin login required)")
+	listRooms := flag.Bool("list-rooms", false, "list defined rooms and their access lists")
+	allowUsers := flag.String("allow-users", "", "comma-separated users for --create-room and --room-access")
+	allowGroups := flag.String("allow-groups", "", "comma-separated groups for --create-room and --room-access")
+	banUser := flag.String("ban-user", "", "ban `user` from --ban-room, or from every room (admin login required)")
+	unbanUser := flag.String("unban-user", "", "lift `user`'s ban from --ban-room, or their global ban (admin login required)")
+	banRoom := flag.String("ban-room", "", "room `name` for --ban-user and --unban-user; empty means every room")
+	banFor := flag.Duration("ban-for", 0, "ban duration for --ban-user (0 is permanent)")
+	banReason := flag.String("ban-reason", "", "reason shown to the user for --ban-user")
+	listBans := flag.Bool("list-bans", false, "list bans in force")
+	historyDir := flag.String("history-dir", "", "store encrypted room history in `dir` and replay it to joining clients")
+	historyReplay := flag.Int("history-replay", 50, "messages replayed on join when history is enabled (max 200)")
+	maxFileSize := flag.Int64("max-file-size", 10<<20, "largest file, in bytes, that can be sent in rooms without their own cap (0 disables file transfer)")
+	clientIPHeader := flag.String("client-ip-header", "", "`header` in which a trusted reverse proxy passes the client address, e.g. X-Forwarded-For; failed logins count against it instead of the proxy")
+	openRooms := flag.Bool("open-rooms", false, "let authenticated users join PINs that have no room definition")
+	encryptDB := flag.Bool("encrypt-db", false, "encrypt an existing plaintext user database, then exit")
+	dbKeyFile := flag.String("db-key-file", "", "file holding the user database key (default: $SECUCHAT_DB_KEY_FILE, $SECUCHAT_DB_PASSPHRASE or prompt)")
+	genCert := flag.Bool("gen-cert", false, "generate a self-signed certificate and key, then exit")
+	addr := flag.String("addr", "", "listen address (default 127.0.0.1:$PORT, PORT defaults to 8080)")
+	certFile := flag.String("tls-cert", "", "TLS certificate file; enables wss:// when set with --tls-key")
+	keyFile := flag.String("tls-key", "", "TLS private key file")
+	hosts := flag.String("hosts", "localhost,127.0.0.1", "comma-separated DNS names and IPs for --gen-cert")
+	certDays := flag.Int("cert-days", 365, "validity in days for --gen-cert")
+	flag.Usage = usage
+	flag.Parse()
+
+	auth.SetLockoutPolicy(auth.LockoutPolicy{
+		MaxFailures: *maxFailures,
+		BaseLockout: *lockoutBase,
+		MaxLockout:  *lockoutMax,
+	})
+
+	// Everything except certificate generation touches the user database
+	var dbVault *vault.Vault
+	if !*genCert {
+		var err error
+		dbVault, err = unlockUserDB(*dbKeyFile, *encryptDB)
+		if err != nil {
+			fmt.Printf("❌ Cannot open user database: %v\n", err)
+			return
+		}
+	}
+
+	switch {
+	case *encryptDB:
+		err := auth.EncryptUserDB()
+		if err != nil {
+			fmt.Printf("❌ Failed to encrypt user database: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ %s is now encrypted at rest.\n", auth.UserDBFile)
+		return
+	case *setup:
+		err := auth.InitialSetup()
+		if err != nil {
+			fmt.Printf("❌ Setup failed: %v\n", err)
+		}
+		return
+	case *listUsers:
+		err := auth.ListUsers()
+		if err != nil {
+			fmt.Printf("❌ Failed to list users: %v\n", err)
+		}
+		return
+	case *enroll2FA:
+		username, _, err := auth.Login()
+		if err != nil {
+			fmt.Printf("❌ Authentication failed: %v\n", err)
+			return
+		}
+		if err := auth.EnrollTOTPInteractive(username); err != nil {
+			fmt.Printf("❌ Enrollment failed: %v\n", err)
+		}
+		return
+	case *reset2FA != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return auth.ResetTOTP(admin, isAdmin, *reset2FA)
+		}, fmt.Sprintf("Two-factor authentication removed for '%s'.", *reset2FA))
+		return
+	case *requireAdmin2FA != "":
+		if *requireAdmin2FA != "on" && *requireAdmin2FA != "off" {
+			fmt.Println("❌ --require-admin-2fa takes on or off")
+			return
+		}
+		require := *requireAdmin2FA == "on"
+		adminAction(func(admin string, isAdmin bool) error {
+			return auth.SetRequireAdminTOTP(admin, isAdmin, require)
+		}, fmt.Sprintf("Two-factor authentication for admins is now %s.", *requireAdmin2FA))
+		return
+	case *listLockouts:
+		err := auth.PrintLockouts()
+		if err != nil {
+			fmt.Printf("❌ Failed to list lockouts: %v\n", err)
+		}
+		return
+	case *createUser:
+		// Need to authenticate first
+		username, isAdmin, err := auth.Login()
+		if err != nil {
+			fmt.Printf("❌ Authentication failed: %v\n", err)
+			return
+		}
+		err = auth.CreateUser(username, isAdmin)
+		if err != nil {
+			fmt.Printf("❌ Failed to create user: %v\n", err)
+		}
+		return
+	case *passwd:
+		err := auth.ChangePassword()
+		if err != nil {
+			fmt.Printf("❌ Password change failed: %v\n", err)
+		}
+		return
+	case *resetPassword != "":
+		username, isAdmin, err := auth.Login()
+		if err != nil {
+			fmt.Printf("❌ Authentication failed: %v\n", err)
+			return
+		}
+		temporary, err := auth.ResetPassword(username, isAdmin, *resetPassword)
+		if err != nil {
+			fmt.Printf("❌ Password reset failed: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Temporary password for '%s': %s\n", *resetPassword, temporary)
+		fmt.Println("   Hand it over out of band; a new password is required at next login.")
+		return
+	case *disableUser != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return auth.DisableUser(admin, isAdmin, *disableUser)
+		}, fmt.Sprintf("Account '%s' disabled.", *disableUser))
+		return
+	case *enableUser != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return auth.EnableUser(admin, isAdmin, *enableUser)
+		}, fmt.Sprintf("Account '%s' re-enabled.", *enableUser))
+		return
+	case *lockUser != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return auth.LockUser(admin, isAdmin, *lockUser, *lockFor)
+		}, fmt.Sprintf("Account '%s' locked for %s.", *lockUser, *lockFor))
+		return
+	case *deleteUser != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return auth.DeleteUser(admin, isAdmin, *deleteUser)
+		}, fmt.Sprintf("Account '%s' deleted.", *deleteUser))
+		return
+	case *unlockUser != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return auth.UnlockUser(admin, isAdmin, *unlockUser)
+		}, fmt.Sprintf("Account '%s' unlocked.", *unlockUser))
+		return
+	case *setGroups != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return auth.SetUserGroups(admin, isAdmin, *setGroups, rooms.SplitList(*groups))
+		}, fmt.Sprintf("Groups for '%s' set to [%s].", *setGroups, *groups))
+		return
+	case *createRoom != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			pin, err := promptRoomPIN()
+			if err != nil {
+				return err
+			}
+			return rooms.CreateRoom(admin, isAdmin, *createRoom, pin, rooms.SplitList(*allowUsers), rooms.SplitList(*allowGroups))
+		}, fmt.Sprintf("Room '%s' defined.", *createRoom))
+		return
+	case *roomAccess != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return rooms.SetAccess(admin, isAdmin, *roomAccess, rooms.SplitList(*allowUsers), rooms.SplitList(*allowGroups))
+		}, fmt.Sprintf("Access list for room '%s' updated.", *roomAccess))
+		return
+	case *roomTTL != "":
+		seconds, err := protocol.ParseTTL(*ttl)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		adminAction(func(admin string, isAdmin bool) error {
+			return rooms.SetRoomTTL(admin, isAdmin, *roomTTL, seconds)
+		}, fmt.Sprintf("Message timer for room '%s' set to %s.", *roomTTL, *ttl))
+		return
+	case *roomTopic != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return rooms.SetRoomTopic(admin, isAdmin, *roomTopic, strings.TrimSpace(*topic))
+		}, fmt.Sprintf("Topic for room '%s' updated.", *roomTopic))
+		return
+	case *roomMOTD != "":
+		var motd []byte
+		if *motdFile != "" {
+			var err error
+			motd, err = os.ReadFile(*motdFile)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+		}
+		adminAction(func(admin string, isAdmin bool) error {
+			return rooms.SetRoomMOTD(admin, isAdmin, *roomMOTD, strings.TrimSpace(string(motd)))
+		}, fmt.Sprintf("Message of the day for room '%s' updated.", *roomMOTD))
+		return
+	case *roomFileCap != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return rooms.SetRoomFileCap(admin, isAdmin, *roomFileCap, *fileCapBytes)
+		}, fmt.Sprintf("File transfer cap for room '%s' updated.", *roomFileCap))
+		return
+	case *deleteRoom != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return rooms.DeleteRoom(admin, isAdmin, *deleteRoom)
+		}, fmt.Sprintf("Room '%s' deleted.", *deleteRoom))
+		return
+	case *listRooms:
+		if err := rooms.ListRooms(); err != nil {
+			fmt.Printf("❌ Failed to list rooms: %v\n", err)
+		}
+		return
+	case *banUser != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return rooms.BanUser(admin, isAdmin, *banRoom, *banUser, *banFor, *banReason)
+		}, fmt.Sprintf("'%s' banned.", *banUser))
+		return
+	case *unbanUser != "":
+		adminAction(func(admin string, isAdmin bool) error {
+			return rooms.UnbanUser(admin, isAdmin, *banRoom, *unbanUser)
+		}, fmt.Sprintf("'%s' unbanned.", *unbanUser))
+		return
+	case *listBans:
+		if err := rooms.ListBans(); err != nil {
+			fmt.Printf("❌ Failed to list bans: %v\n", err)
+		}
+		return
+	case *genCert:
+		if *certFile == "" {
+			*certFile = "secuchat.crt"
+		}
+		if *keyFile == "" {
+			*keyFile = "secuchat.key"
+		}
+		validFor := time.Duration(*certDays) * 24 * time.Hour
+		fingerprint, err := tlsutil.GenerateSelfSigned(*certFile, *keyFile, strings.Split(*hosts, ","), validFor)
+		if err != nil {
+			fmt.Printf("❌ Certificate generation failed: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Wrote %s and %s for %s\n", *certFile, *keyFile, *hosts)
+		fmt.Printf("🔑 SHA-256 fingerprint: %s\n", fingerprint)
+		fmt.Printf("   Clients connect with: secuchat --fingerprint %s wss://<host>:<port>/ws <pin>\n", fingerprint)
+		return
+	}
+
+	useTLS := *certFile != "" || *keyFile != ""
+	if useTLS && (*certFile == "" || *keyFile == "") {
+		fmt.Println("❌ --tls-cert and --tls-key must be given together")
+		return
+	}
+
+	// Terms of Service acceptance via Python
+	fmt.Println("🔒 Secuchat-CLI - Red Team Communications")
+	if !CallPythonToS() {
+		fmt.Println("❌ Terms not accepted. Exiting.")
+		return
+	}
+	fmt.Println("✅ Terms accepted. Starting server...")
+	time.Sleep(2 * time.Second)
+
+	listenAddr := *addr
+	if listenAddr == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		listenAddr = "127.0.0.1:" + port
+	}
+
+	if sealed, err := auth.UserDBSealed(); err == nil && !sealed {
+		log.Printf("⚠️  %s is stored in plaintext; run --encrypt-db to seal it", auth.UserDBFile)
+	}
+
+	sessions, err := auth.NewSessionManager()
+	if err != nil {
+		log.Fatalf("Session setup failed: %v", err)
+	}
+
+	if db, err := rooms.Load(); err != nil {
+		log.Fatalf("Cannot load room definitions: %v", err)
+	} else if len(db.Rooms) == 0 && !*openRooms {
+		log.Printf("⚠️  No rooms defined; every join will be refused. Use --create-room or --open-rooms.")
+	}
+
+	opts := hub.Options{OpenRooms: *openRooms, HistoryReplay: *historyReplay, MaxFileSize: *maxFileSize, ClientIPHeader: *clientIPHeader}
+	if *historyDir != "" {
+		opts.History, err = history.NewStore(*historyDir, dbVault)
+		if err != nil {
+			log.Fatalf("History setup failed: %v", err)
+		}
+		log.Printf("📜 Room history is stored encrypted in %s", *historyDir)
+	}
+
+	manager := hub.NewHubManager(sessions, opts)
+	mux := http.NewServeMux()
+
+	// --- WebSocket route ---
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		hub.ServeWs(manager, w, r)
+	})
+
+	// --- Admin room API ---
+	mux.HandleFunc("/api/rooms", func(w http.ResponseWriter, r *http.Request) {
+		hub.ServeRoomsAPI(manager, w, r)
+	})
+	mux.HandleFunc("/api/rooms/", func(w http.ResponseWriter, r *http.Request) {
+		hub.ServeRoomsAPI(manager, w, r)
+	})
+
+	// --- Health check ---
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	server := &http.Server{
+		Addr:         listenAddr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	if !useTLS {
+		log.Printf("✅ Server running on ws://%s/ws", listenAddr)
+		log.Fatal(server.ListenAndServe())
+	}
+
+	fingerprint, err := tlsutil.CertFileFingerprint(*certFile)
+	if err != nil {
+		log.Fatalf("TLS setup failed: %v", err)
+	}
+	server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	log.Printf("✅ Server running on wss://%s/ws", listenAddr)
+	log.Printf("🔑 Certificate SHA-256 fingerprint: %s", fingerprint)
+	log.Fatal(server.ListenAndServeTLS(*certFile, *keyFile))
+}
+
+// adminAction logs an admin in and runs action as them.
+func adminAction(action func(admin string, isAdmin bool) error, success string) {
+	username, isAdmin, err := auth.Login()
+	if err != nil {
+		fmt.Printf("❌ Authentication failed: %v\n", err)
+		return
+	}
+	if err := action(username, isAdmin); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	fmt.Printf("✅ %s\n", success)
+}
+
+// promptRoomPIN asks for a new room's PIN twice without echoing it.
+func promptRoomPIN() (string, error) {
+	pin, err := auth.ReadPassword("Room PIN: ")
+	if err != nil {
+		return "", err
+	}
+	confirm, err := auth.ReadPassword("Confirm PIN: ")
+	if err != nil {
+		return "", err
+	}
+	if pin != confirm {
+		return "", fmt.Errorf("PINs do not match")
+	}
+	return pin, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+	"github.com/EJ-Edwards/Secuchat-CLI/vault"
+)
+
+// unlockUserDB configures the key that seals the user database at rest. The
+// secret comes from --db-key-file, SECUCHAT_DB_KEY_FILE or
+// SECUCHAT_DB_PASSPHRASE, and otherwise from an interactive prompt. The
+// returned vault also seals room definitions and history. With confirm set,
+// a prompted passphrase is always typed twice.
+func unlockUserDB(keyFile string, confirm bool) (*vault.Vault, error) {
+	var secret []byte
+	var err error
+	if keyFile != "" {
+		secret, err = vault.ReadKeyFile(keyFile)
+	} else {
+		secret, err = vault.SecretFromEnv()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if secret == nil {
+		secret, err = promptPassphrase(confirm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	v, err := vault.New(secret)
+	if err != nil {
+		return nil, err
+	}
+	auth.UseVault(v)
+	rooms.UseVault(v)
+
+	// Fail early on a wrong passphrase rather than at the first login
+	if _, err := auth.LoadUsers(); err != nil {
+		return nil, err
+	}
+	// Password hashes from older versions can log in as their users
+	upgraded, err := auth.UpgradeUserDB()
+	if err != nil {
+		return nil, err
+	}
+	if upgraded {
+		fmt.Printf("🔑 Replaced stored password hashes in %s with login verifiers.\n", auth.UserDBFile)
+	}
+	return v, nil
+}
+
+// promptPassphrase asks for the user database passphrase. LoadUsers accepts
+// any key for a plaintext or missing database and the next write seals it
+// under what was typed, so then a new passphrase is chosen: typed twice and
+// at least 12 characters. With confirm set, an existing passphrase is typed
+// twice as well.
+func promptPassphrase(confirm bool) ([]byte, error) {
+	data, err := os.ReadFile(auth.UserDBFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sealed := err == nil && vault.IsSealed(data)
+
+	prompt := "🔐 User database passphrase: "
+	if !sealed {
+		fmt.Println("🔐 Choose a passphrase to encrypt the user database.")
+		prompt = "New database passphrase: "
+	}
+	passphrase, err := auth.ReadPassword(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if sealed && !confirm {
+		return []byte(passphrase), nil
+	}
+
+	again, err := auth.ReadPassword("Confirm passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if passphrase != again {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	if !sealed && len(passphrase) < 12 {
+		return nil, fmt.Errorf("passphrase must be at least 12 characters")
+	}
+	return []byte(passphrase), nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/tlsutil"
+	"github.com/gorilla/websocket"
+)
+
+// newDialer returns the default dialer, or one that only trusts the server
+// certificate matching fingerprint when it is set.
+func newDialer(u *url.URL, fingerprint string) (*websocket.Dialer, error) {
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = protocol.Subprotocols()
+	if fingerprint == "" {
+		return &dialer, nil
+	}
+
+	if u.Scheme != "wss" {
+		return nil, fmt.Errorf("--fingerprint requires a wss:// server URL")
+	}
+	config, err := tlsutil.PinnedConfig(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	dialer.TLSClientConfig = config
+	return &dialer, nil
+}
+
+// refusedError is the server declining the WebSocket upgrade over HTTP.
+type refusedError struct {
+	status int
+	reason string
+}
+
+func (e *refusedError) Error() string {
+	return e.reason
+}
+
+// temporary reports whether trying again later may succeed, as opposed to
+// the server refusing the session or the room outright.
+func (e *refusedError) temporary() bool {
+	return e.status >= 500 || e.status == http.StatusTooManyRequests
+}
+
+// dial connects to the server and returns the negotiated protocol version.
+// Servers that predate negotiation select no subprotocol and speak version
+// 1. When the server refuses the upgrade, a *refusedError carries its reason.
+func dial(dialer *websocket.Dialer, u *url.URL, header http.Header) (*websocket.Conn, int, error) {
+	conn, resp, err := dialer.Dial(u.String(), header)
+	if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		_ = resp.Body.Close()
+		refused := &refusedError{status: resp.StatusCode}
+		if reason := strings.TrimSpace(string(body)); reason != "" {
+			refused.reason = fmt.Sprintf("%s (%s)", reason, resp.Status)
+		} else {
+			refused.reason = fmt.Sprintf("server refused the connection (%s)", resp.Status)
+		}
+		return nil, 0, refused
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	version := 1
+	if name := conn.Subprotocol(); name != "" {
+		version, err = protocol.ParseSubprotocol(name)
+		if err != nil {
+			_ = conn.Close()
+			return nil, 0, err
+		}
+	}
+	return conn, version, nil
+}
+
+// writeWait bounds one write, so a dead connection cannot stall the client.
+const writeWait = 10 * time.Second
+
+// errDisconnected is returned for frames written while the client is
+// reconnecting.
+var errDisconnected = errors.New("not connected")
+
+// wire serializes frames written to the connection, which the input loop,
+// the reader and file uploads all send on. It outlives each connection:
+// between a drop and the reconnect it has none.
+type wire struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// WriteJSON sends v, closing the connection when the write fails so the
+// reader notices the drop.
+func (w *wire) WriteJSON(v any) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return errDisconnected
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	err := w.conn.WriteJSON(v)
+	if err != nil {
+		_ = w.conn.Close()
+	}
+	return err
+}
+
+// attach makes conn the connection frames are written to.
+func (w *wire) attach(conn *websocket.Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.conn = conn
+}
+
+// detach closes conn and stops writing to it, unless a newer connection
+// has already replaced it.
+func (w *wire) detach(conn *websocket.Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == conn {
+		w.conn = nil
+	}
+	_ = conn.Close()
+}
+
+// close ends the current connection, if any.
+func (w *wire) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+)
+
+const (
+	// chunkTimeout is how long a download waits for its next chunk before
+	// asking for the window again; the server drops chunks for clients
+	// whose buffer is full.
+	chunkTimeout = 10 * time.Second
+
+	// maxChunkRetries is how many times a stalled download asks again
+	// before the user is told to resume it with /accept.
+	maxChunkRetries = 3
+)
+
+// newFileID names an upload; it is short enough to type back with /accept.
+func newFileID() (string, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// sealFile seals part of a file offer or its data like a chat body. Without
+// a room key it is left readable: text as is, data base64 encoded.
+func sealFile(key *e2e.RoomKey, username string, data []byte, text bool) (string, error) {
+	if key != nil {
+		return key.Seal(username, data)
+	}
+	if text {
+		return string(data), nil
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// openFile reverses sealFile for a frame from sender.
+func openFile(key *e2e.RoomKey, msg protocol.Message, sender, value string, text bool) ([]byte, error) {
+	switch {
+	case msg.Enc == "" && text:
+		return []byte(value), nil
+	case msg.Enc == "":
+		return base64.StdEncoding.DecodeString(value)
+	case key == nil:
+		return nil, fmt.Errorf("file is encrypted; end-to-end encryption is disabled")
+	case msg.Enc != e2e.Algorithm:
+		return nil, fmt.Errorf("unsupported cipher %q", msg.Enc)
+	}
+	return key.Open(sender, value)
+}
+
+// prepareUpload reads the file at path and builds the offer and chunk
+// frames that send it, sealing its name, digest and data when the room is
+// encrypted. limit is the room's cap from room_info.
+func prepareUpload(key *e2e.RoomKey, username, path string, limit int64) ([]protocol.Message, error) {
+	if limit == 0 {
+		return nil, fmt.Errorf("file transfer is disabled in this room")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !info.Mode().IsRegular():
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	case info.Size() == 0:
+		return nil, fmt.Errorf("%s is empty", path)
+	case info.Size() > limit:
+		return nil, fmt.Errorf("%s is %s; this room accepts files up to %s",
+			path, protocol.FormatSize(info.Size()), protocol.FormatSize(limit))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newFileID()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	name, err := sealFile(key, username, []byte(filepath.Base(path)), true)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := sealFile(key, username, []byte(hex.EncodeToString(sum[:])), true)
+	if err != nil {
+		return nil, err
+	}
+	enc := ""
+	if key != nil {
+		enc = e2e.Algorithm
+	}
+
+	size := int64(len(data))
+	chunks := protocol.FileChunks(size)
+	frames := make([]protocol.Message, 0, 1+chunks)
+	frames = append(frames, protocol.Message{
+		Type:     protocol.TypeFileOffer,
+		Message:  name,
+		Username: username,
+		Enc:      enc,
+		File:     &protocol.FileInfo{ID: id, Size: size, Chunks: chunks, SHA256: digest},
+	})
+	for index := 0; index < chunks; index++ {
+		part := data[index*protocol.FileChunkSize : min((index+1)*protocol.FileChunkSize, len(data))]
+		payload, err := sealFile(key, username, part, false)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, protocol.Message{
+			Type:    protocol.TypeFileChunk,
+			Message: payload,
+			Enc:     enc,
+			File:    &protocol.FileInfo{ID: id, Index: index},
+		})
+	}
+	return frames, nil
+}
+
+// download is a file offered in the room. chunks is set while the user is
+// downloading it, and requested is the end of the window last asked for
+// while waiting on it.
+type download struct {
+	from     string
+	name     string
+	size     int64
+	sha256   string
+	chunks   [][]byte
+	received int
+
+	requested int
+	progress  time.Time
+	retries   int
+}
+
+// nextChunk is the first chunk not yet received.
+func (d *download) nextChunk() int {
+	for index, chunk := range d.chunks {
+		if chunk == nil {
+			return index
+		}
+	}
+	return len(d.chunks)
+}
+
+// request asks for the window starting at the first missing chunk.
+func (d *download) request(id string) protocol.Message {
+	start := d.nextChunk()
+	d.requested = min(start+protocol.FileWindow, len(d.chunks))
+	d.progress = time.Now()
+	return protocol.Message{
+		Type: protocol.TypeFileAccept,
+		File: &protocol.FileInfo{ID: id, Index: start},
+	}
+}
+
+// downloads tracks the files offered since the client joined and the ones
+// the user accepted. The reader goroutine and the input loop share it.
+type downloads struct {
+	mu     sync.Mutex
+	key    *e2e.RoomKey
+	self   string
+	dir    string
+	offers map[string]*download
+}
+
+func newDownloads(key *e2e.RoomKey, self, dir string) *downloads {
+	return &downloads{key: key, self: self, dir: dir, offers: make(map[string]*download)}
+}
+
+// useKey switches to the room key of a new connection after a reconnect.
+func (d *downloads) useKey(key *e2e.RoomKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.key = key
+}
+
+// offered records a file_offer frame and returns how to show it.
+func (d *downloads) offered(msg protocol.Message) string {
+	if msg.File == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	timestamp := ""
+	if t, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
+		timestamp = "[" + t.Format("15:04") + "] "
+	}
+	name, err := openFile(d.key, msg, msg.Username, msg.Message, true)
+	var digest []byte
+	if err == nil {
+		digest, err = openFile(d.key, msg, msg.Username, msg.File.SHA256, true)
+	}
+	if err != nil {
+		return fmt.Sprintf("⚠️ [UNVERIFIED] %s%s offered a file that cannot be opened (%v — wrong PIN or tampered offer)", timestamp, msg.Username, err)
+	}
+
+	offer := &download{
+		from:   msg.Username,
+		name:   safeFileName(string(name)),
+		size:   msg.File.Size,
+		sha256: string(digest),
+	}
+	d.offers[msg.File.ID] = offer
+	if msg.Username == d.self {
+		return fmt.Sprintf("📎 %sYou shared %s (%s) as %s", timestamp, offer.name, protocol.FormatSize(offer.size), msg.File.ID)
+	}
+	return fmt.Sprintf("📎 %s%s offers %s (%s) — /accept %s", timestamp, msg.Username, offer.name, protocol.FormatSize(offer.size), msg.File.ID)
+}
+
+// accept starts or resumes downloading file id, returning the request for
+// its next window of chunks and a notice for the user.
+func (d *downloads) accept(id string) (protocol.Message, string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	offer, ok := d.offers[id]
+	if !ok {
+		return protocol.Message{}, "", fmt.Errorf("no file %s has been offered since you joined", id)
+	}
+	if offer.chunks == nil {
+		offer.chunks = make([][]byte, protocol.FileChunks(offer.size))
+		offer.received = 0
+	}
+	offer.retries = 0
+	request := offer.request(id)
+	return request, fmt.Sprintf("📥 Downloading %s (%s) from %s...", offer.name, protocol.FormatSize(offer.size), offer.from), nil
+}
+
+// received stores a file_chunk frame. It returns the request for the next
+// window once every chunk asked for has arrived, and a notice once the
+// download succeeds or fails.
+func (d *downloads) received(msg protocol.Message) (*protocol.Message, string) {
+	if msg.File == nil {
+		return nil, ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	offer, ok := d.offers[msg.File.ID]
+	index := msg.File.Index
+	if !ok || index < 0 || index >= len(offer.chunks) || offer.chunks[index] != nil {
+		return nil, ""
+	}
+
+	data, err := openFile(d.key, msg, offer.from, msg.Message, false)
+	if err == nil && (len(data) == 0 || len(data) > protocol.FileChunkSize) {
+		err = fmt.Errorf("bad chunk size")
+	}
+	if err != nil {
+		offer.chunks = nil
+		offer.requested = 0
+		return nil, fmt.Sprintf("❌ Download of %s failed: chunk %d cannot be opened (%v — wrong PIN or tampered file)", offer.name, index, err)
+	}
+	offer.chunks[index] = data
+	offer.received++
+	offer.progress = time.Now()
+	offer.retries = 0
+
+	if offer.received == len(offer.chunks) {
+		status := d.save(offer)
+		offer.chunks = nil
+		offer.requested = 0
+		return nil, status
+	}
+	if offer.requested > 0 && offer.nextChunk() >= offer.requested {
+		request := offer.request(msg.File.ID)
+		return &request, ""
+	}
+	return nil, ""
+}
+
+// stalled asks again for the window of every download that has waited
+// longer than chunkTimeout, covering chunks the server dropped. After
+// maxChunkRetries it gives up and returns a notice instead; /accept resumes
+// the download.
+func (d *downloads) stalled(now time.Time) ([]protocol.Message, []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var requests []protocol.Message
+	var notices []string
+	for id, offer := range d.offers {
+		if offer.requested == 0 || now.Sub(offer.progress) < chunkTimeout {
+			continue
+		}
+		if offer.retries >= maxChunkRetries {
+			offer.requested = 0
+			notices = append(notices, fmt.Sprintf("⚠️ Download of %s stalled at %d/%d chunks — /accept %s to resume", offer.name, offer.received, len(offer.chunks), id))
+			continue
+		}
+		offer.retries++
+		requests = append(requests, offer.request(id))
+	}
+	return requests, notices
+}
+
+// save verifies a complete download against the offered digest and writes
+// it to the download directory.
+func (d *downloads) save(offer *download) string {
+	data := bytes.Join(offer.chunks, nil)
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != offer.size || !strings.EqualFold(hex.EncodeToString(sum[:]), offer.sha256) {
+		return fmt.Sprintf("❌ %s from %s failed SHA-256 verification and was discarded", offer.name, offer.from)
+	}
+	path, err := saveFile(d.dir, offer.name, data)
+	if err != nil {
+		return fmt.Sprintf("❌ Saving %s failed: %v", offer.name, err)
+	}
+	return fmt.Sprintf("✅ Saved %s (%s, SHA-256 verified)", path, protocol.FormatSize(offer.size))
+}
+
+// safeFileName keeps only the last element of an offered name, so a sender
+// cannot choose where the file is written.
+func safeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == ".." || name == "/" || strings.TrimSpace(name) == "" {
+		return "download"
+	}
+	return name
+}
+
+// saveFile writes data to name in dir without replacing an existing file,
+// numbering the name instead: "report (1).pdf".
+func saveFile(dir, name string, data []byte) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 0; n < 100; n++ {
+		candidate := name
+		if n > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", stem, n, ext)
+		}
+		path := filepath.Join(dir, candidate)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.Write(data); err != nil {
+			_ = file.Close()
+			return "", err
+		}
+		return path, file.Close()
+	}
+	return "", fmt.Errorf("too many files named %s in %s", name, dir)
+}
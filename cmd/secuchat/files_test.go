@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+)
+
+func TestDownloadRecoversDroppedChunks(t *testing.T) {
+	data := bytes.Repeat([]byte("secuchat"), protocol.FileChunkSize*(protocol.FileWindow+4)/8)
+	sum := sha256.Sum256(data)
+	chunks := protocol.FileChunks(int64(len(data)))
+	chunk := func(index int) protocol.Message {
+		part := data[index*protocol.FileChunkSize : min((index+1)*protocol.FileChunkSize, len(data))]
+		return protocol.Message{
+			Type:    protocol.TypeFileChunk,
+			Message: base64.StdEncoding.EncodeToString(part),
+			File:    &protocol.FileInfo{ID: "f1", Index: index},
+		}
+	}
+
+	dir := t.TempDir()
+	files := newDownloads(nil, "bob", dir)
+	files.offered(protocol.Message{
+		Type:     protocol.TypeFileOffer,
+		Message:  "report.bin",
+		Username: "alice",
+		File:     &protocol.FileInfo{ID: "f1", Size: int64(len(data)), Chunks: chunks, SHA256: hex.EncodeToString(sum[:])},
+	})
+	request, _, err := files.accept("f1")
+	if err != nil || request.File.Index != 0 {
+		t.Fatalf("accept() = %+v, %v; want a request from chunk 0", request, err)
+	}
+
+	// The server drops chunk 3 of the first window
+	for index := 0; index < protocol.FileWindow; index++ {
+		if index == 3 {
+			continue
+		}
+		if next, status := files.received(chunk(index)); next != nil || status != "" {
+			t.Fatalf("received(%d) = %+v, %q; want to keep waiting", index, next, status)
+		}
+	}
+	if requests, _ := files.stalled(time.Now()); len(requests) != 0 {
+		t.Fatalf("stalled() before the timeout = %+v, want none", requests)
+	}
+	requests, _ := files.stalled(time.Now().Add(chunkTimeout))
+	if len(requests) != 1 || requests[0].File.Index != 3 {
+		t.Fatalf("stalled() = %+v, want one request from chunk 3", requests)
+	}
+
+	// The retried window runs from chunk 3; once it is used up the next one
+	// is requested
+	retried := 3 + protocol.FileWindow
+	for index := 3; index < retried-1; index++ {
+		if next, _ := files.received(chunk(index)); next != nil {
+			t.Fatalf("received(%d) = %+v, want to keep waiting", index, next)
+		}
+	}
+	next, _ := files.received(chunk(retried - 1))
+	if next == nil || next.File.Index != retried {
+		t.Fatalf("received(%d) = %+v, want a request from chunk %d", retried-1, next, retried)
+	}
+	var status string
+	for index := retried; index < chunks; index++ {
+		_, status = files.received(chunk(index))
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "report.bin"))
+	if err != nil || !bytes.Equal(saved, data) {
+		t.Fatalf("download not saved (%q): %v", status, err)
+	}
+}
+
+func TestDownloadGivesUpAfterRetries(t *testing.T) {
+	files := newDownloads(nil, "bob", t.TempDir())
+	files.offered(protocol.Message{
+		Type:     protocol.TypeFileOffer,
+		Message:  "report.bin",
+		Username: "alice",
+		File:     &protocol.FileInfo{ID: "f1", Size: 10, Chunks: 1, SHA256: "00"},
+	})
+	if _, _, err := files.accept("f1"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for retry := 1; retry <= maxChunkRetries; retry++ {
+		now = now.Add(chunkTimeout)
+		if requests, notices := files.stalled(now); len(requests) != 1 || len(notices) != 0 {
+			t.Fatalf("retry %d: stalled() = %+v, %q; want one request", retry, requests, notices)
+		}
+	}
+	requests, notices := files.stalled(now.Add(2 * chunkTimeout))
+	if len(requests) != 0 || len(notices) != 1 {
+		t.Fatalf("stalled() = %+v, %q; want a notice", requests, notices)
+	}
+	if requests, notices := files.stalled(now.Add(4 * chunkTimeout)); len(requests) != 0 || len(notices) != 0 {
+		t.Fatalf("stalled() after giving up = %+v, %q; want nothing", requests, notices)
+	}
+}
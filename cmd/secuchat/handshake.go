@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/gorilla/websocket"
+)
+
+const handshakeTimeout = 30 * time.Second
+
+// clientHandshake proves knowledge of password to the server and returns the
+// server's auth_ok reply, which carries the session token and role. With
+// changePassword set, or when an admin reset is pending, the user is asked
+// for a new password during the exchange.
+func clientHandshake(conn *websocket.Conn, username, password string, changePassword bool) (protocol.AuthMessage, error) {
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	if err := conn.WriteJSON(protocol.AuthMessage{Type: protocol.TypeAuthHello, Username: username}); err != nil {
+		return protocol.AuthMessage{}, err
+	}
+
+	var challenge protocol.AuthMessage
+	if err := conn.ReadJSON(&challenge); err != nil {
+		return protocol.AuthMessage{}, err
+	}
+	if challenge.Type != protocol.TypeAuthChallenge {
+		return protocol.AuthMessage{}, fmt.Errorf("unexpected server reply %q", challenge.Type)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(challenge.Nonce)
+	if err != nil || len(nonce) != auth.NonceSize {
+		return protocol.AuthMessage{}, fmt.Errorf("invalid challenge nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(challenge.Salt)
+	if err != nil {
+		return protocol.AuthMessage{}, fmt.Errorf("invalid challenge salt")
+	}
+
+	key := auth.LoginKey(password, salt)
+
+	response := protocol.AuthMessage{
+		Type:           protocol.TypeAuthResponse,
+		Proof:          base64.StdEncoding.EncodeToString(auth.ComputeProof(key, nonce, username)),
+		ChangePassword: changePassword,
+	}
+	if err := conn.WriteJSON(response); err != nil {
+		return protocol.AuthMessage{}, err
+	}
+
+	var result protocol.AuthMessage
+	if err := conn.ReadJSON(&result); err != nil {
+		return protocol.AuthMessage{}, err
+	}
+
+	if result.Type == protocol.TypeAuthTOTPRequired || result.Type == protocol.TypeAuthTOTPEnroll {
+		if err := sendTOTPCode(conn, result); err != nil {
+			return protocol.AuthMessage{}, err
+		}
+		conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+		if err := conn.ReadJSON(&result); err != nil {
+			return protocol.AuthMessage{}, err
+		}
+	}
+
+	if result.Type == protocol.TypeAuthChangeRequired {
+		if err := sendPasswordChange(conn, username, password, nonce, result.Message); err != nil {
+			return protocol.AuthMessage{}, err
+		}
+		conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+		if err := conn.ReadJSON(&result); err != nil {
+			return protocol.AuthMessage{}, err
+		}
+	}
+
+	switch result.Type {
+	case protocol.TypeAuthOK:
+		return result, nil
+	case protocol.TypeAuthFailed:
+		return protocol.AuthMessage{}, fmt.Errorf("%s", result.Message)
+	default:
+		return protocol.AuthMessage{}, fmt.Errorf("unexpected server reply %q", result.Type)
+	}
+}
+
+// sendTOTPCode answers the server's second-factor request, showing the
+// enrollment URI first when the account still has to set up 2FA.
+func sendTOTPCode(conn *websocket.Conn, request protocol.AuthMessage) error {
+	fmt.Printf("🔐 %s\n", request.Message)
+	if request.Type == protocol.TypeAuthTOTPEnroll {
+		fmt.Printf("   %s\n", request.URI)
+		fmt.Println("   Tip: `qrencode -t ansiutf8 '<uri>'` renders the URI as a QR code.")
+	}
+
+	code, err := auth.PromptTOTPCode("Verification code: ")
+	if err != nil {
+		return err
+	}
+	return conn.WriteJSON(protocol.AuthMessage{Type: protocol.TypeAuthTOTP, Code: code})
+}
+
+// sendPasswordChange prompts for a new password and sends its salt and
+// verifier, signing the login nonce with the new key to prove it. The server
+// never sees the password itself, so the password rules are checked here.
+func sendPasswordChange(conn *websocket.Conn, username, oldPassword string, nonce []byte, prompt string) error {
+	fmt.Printf("🔑 %s\n", prompt)
+	newPassword, err := auth.PromptNewPassword("New password: ")
+	if err != nil {
+		return err
+	}
+	if newPassword == oldPassword {
+		return fmt.Errorf("new password must differ from the current one")
+	}
+
+	salt, err := auth.GenerateSalt()
+	if err != nil {
+		return err
+	}
+
+	key := auth.LoginKey(newPassword, salt)
+	return conn.WriteJSON(protocol.AuthMessage{
+		Type:     protocol.TypeAuthChange,
+		Salt:     base64.StdEncoding.EncodeToString(salt),
+		Verifier: auth.KeyVerifier(key),
+		Proof:    base64.StdEncoding.EncodeToString(auth.ComputeProof(key, nonce, username)),
+	})
+}
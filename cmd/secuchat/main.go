@@ -0,0 +1,576 @@
+// Command secuchat is the terminal client for joining Secuchat rooms.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/gorilla/websocket"
+)
+
+func usage() {
+	fmt.Println("Secuchat-CLI v1.2.0")
+	fmt.Println("===================")
+	fmt.Println("Usage:")
+	fmt.Println("  secuchat [options] <server_url> [pin]   - Join chat")
+	fmt.Println("  secuchat --passwd <server_url>          - Change your password")
+	fmt.Println("  secuchat --token <server_url>           - Print a session token for the admin API")
+	fmt.Println("")
+	fmt.Println("Options:")
+	flag.CommandLine.SetOutput(os.Stdout)
+	flag.PrintDefaults()
+	fmt.Println("")
+	fmt.Println("Examples:")
+	fmt.Println("  secuchat ws://127.0.0.1:8080/ws REDTEAM01")
+	fmt.Println("  secuchat --fingerprint AB:CD:... wss://10.10.0.5:8443/ws REDTEAM01")
+	fmt.Println("")
+	fmt.Println("User accounts are managed on the server host with secuchat-server.")
+}
+
+func main() {
+	noE2E := flag.Bool("no-e2e", false, "send and expect plaintext room messages")
+	fingerprint := flag.String("fingerprint", "", "pin the server's TLS certificate by SHA-256 fingerprint (wss:// only)")
+	passwd := flag.Bool("passwd", false, "change your password on the server, then exit")
+	token := flag.Bool("token", false, "log in and print a session token for the server's HTTP API, then exit")
+	downloadDir := flag.String("download-dir", ".", "directory that files received with /accept are saved in")
+	since := flag.Uint64("since", 0, "replay room history after this sequence number instead of the latest messages")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		usage()
+		return
+	}
+	encrypt := !*noE2E
+
+	serverURL := args[0]
+	if *passwd {
+		if err := changePassword(serverURL, *fingerprint); err != nil {
+			fmt.Printf("❌ Password change failed: %v\n", err)
+		}
+		return
+	}
+	if *token {
+		if err := printToken(serverURL, *fingerprint); err != nil {
+			fmt.Printf("❌ Login failed: %v\n", err)
+		}
+		return
+	}
+
+	pin := "GENERAL"
+	if len(args) > 1 {
+		pin = args[1]
+	}
+
+	// With end-to-end encryption the server only ever sees a room ID
+	// derived from the PIN, never the PIN itself.
+	room := pin
+	if encrypt {
+		room = e2e.RoomID(pin)
+	}
+
+	fmt.Println("🔒 Secuchat-CLI Authentication")
+	fmt.Println("===============================")
+	username, password, err := auth.PromptCredentials()
+	if err != nil {
+		fmt.Printf("❌ Login failed: %v\n", err)
+		return
+	}
+
+	// Add room to URL; identity is established by the login handshake
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		log.Fatal("Invalid server URL:", err)
+	}
+	q := u.Query()
+	q.Set("pin", room)
+	if *since > 0 {
+		q.Set("since", strconv.FormatUint(*since, 10))
+	}
+	u.RawQuery = q.Encode()
+
+	fmt.Printf("🔗 Connecting to room %s as %s...\n", pin, username)
+
+	dialer, err := newDialer(u, *fingerprint)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	// Connect to WebSocket
+	conn, version, err := dial(dialer, u, nil)
+	if err != nil {
+		log.Fatal("Connection failed: ", err)
+	}
+	defer conn.Close()
+
+	// Prove the password to the server; it holds the user database
+	session, err := clientHandshake(conn, username, password, false)
+	if err != nil {
+		fmt.Printf("❌ Login failed: %v\n", err)
+		return
+	}
+	username = session.Username
+	isAdmin := session.IsAdmin
+
+	role := "USER"
+	if isAdmin {
+		role = "ADMIN"
+	}
+	fmt.Printf("✅ Authentication successful! Joining room %s as %s [%s]\n", pin, username, role)
+
+	roomKey, info, err := joinRoom(conn, pin, encrypt, version)
+	if err != nil {
+		fmt.Printf("❌ Failed to join room: %v\n", err)
+		return
+	}
+	maxFileSize, roomSalt := info.MaxFileSize, info.Salt
+
+	fmt.Printf("✅ Connected to Secuchat-CLI room: %s\n", pin)
+	if roomKey != nil {
+		fmt.Println("🔐 Messages are end-to-end encrypted with the room PIN.")
+	} else {
+		fmt.Println("⚠️  End-to-end encryption disabled; the server can read messages.")
+	}
+	fmt.Println("📝 Type messages and press Enter. Type '/quit' to exit.")
+	fmt.Println("---")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	screen := &transcript{}
+	go screen.expireLoop(ctx)
+
+	out := &wire{conn: conn}
+	defer out.close()
+	files := newDownloads(roomKey, username, *downloadDir)
+
+	// lastSeq is the newest message seen, where history resumes after a
+	// reconnect
+	var lastSeq atomic.Uint64
+	lastSeq.Store(*since)
+
+	// Handle incoming messages, one reader per connection; lost reports
+	// why it ended
+	lost := make(chan error, 1)
+	read := func(conn *websocket.Conn, key *e2e.RoomKey) {
+		keepAlive(conn)
+		for {
+			var msg protocol.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				out.detach(conn)
+				lost <- err
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(idleTimeout))
+
+			switch msg.Type {
+			case protocol.TypeSystem:
+				screen.print(fmt.Sprintf("🔔 %s", msg.Message))
+			case protocol.TypeMessage:
+				if msg.Seq > lastSeq.Load() {
+					lastSeq.Store(msg.Seq)
+				}
+				screen.addMessage(msg, formatChat(key, msg))
+			case protocol.TypeEdit:
+				screen.replace(msg.Seq, formatChat(key, msg))
+			case protocol.TypeDelete:
+				screen.replace(msg.Seq, formatDeleted(msg))
+			case protocol.TypeRoster:
+				screen.print(formatRoster(msg))
+			case protocol.TypePresence:
+				if text := formatPresence(msg); text != "" {
+					screen.print(text)
+				}
+			case protocol.TypeDM:
+				screen.print(formatDM(username, msg))
+			case protocol.TypeFileOffer:
+				if text := files.offered(msg); text != "" {
+					screen.print(text)
+				}
+			case protocol.TypeFileChunk:
+				next, status := files.received(msg)
+				if next != nil {
+					if err := out.WriteJSON(*next); err != nil {
+						screen.print(fmt.Sprintf("❌ Send error: %v; /accept %s again to resume", err, msg.File.ID))
+					}
+				}
+				if status != "" {
+					screen.print(status)
+				}
+			case protocol.TypePong:
+				// Handle pong silently
+			default:
+				screen.print(fmt.Sprintf("📨 %s", msg.Message))
+			}
+		}
+	}
+	go read(conn, roomKey)
+
+	// Handle user input
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			select {
+			case lines <- strings.TrimSpace(scanner.Text()):
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Printf("❌ Input error: %v\n", err)
+		}
+	}()
+
+	// Handle Ctrl+C gracefully
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		fmt.Println("\n👋 Disconnecting...")
+		cancel()
+		out.close()
+		os.Exit(0)
+	}()
+
+	// send handles one line of input for the server, returning the error
+	// when its frame could not be sent.
+	send := func(input string) error {
+		if isServerCommand(input) {
+			// Let the server handle command validation; commands are
+			// addressed to the server so they are never encrypted
+			msg := protocol.Message{
+				Type:      protocol.TypeMessage,
+				Message:   input,
+				Username:  username,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+			return out.WriteJSON(msg)
+		}
+
+		if name, args, _ := strings.Cut(input, " "); name == "/edit" || name == "/delete" {
+			var msg protocol.Message
+			var err error
+			if name == "/edit" {
+				msg, err = parseEdit(roomKey, username, input)
+			} else {
+				msg, err = parseDelete(input)
+			}
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return nil
+			}
+			return out.WriteJSON(msg)
+		} else if name == "/send" {
+			path := strings.TrimSpace(args)
+			if path == "" {
+				fmt.Println("❌ Usage: /send <path>")
+				return nil
+			}
+			frames, err := prepareUpload(roomKey, username, path, maxFileSize)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return nil
+			}
+			fmt.Printf("📤 Uploading %s (%s)...\n", filepath.Base(path), protocol.FormatSize(frames[0].File.Size))
+			// Large files take a while; keep the chat usable meanwhile
+			go func() {
+				for _, frame := range frames {
+					if err := out.WriteJSON(frame); err != nil {
+						screen.print(fmt.Sprintf("❌ Upload of %s failed: %v", filepath.Base(path), err))
+						return
+					}
+				}
+			}()
+			return nil
+		} else if name == "/accept" {
+			id := strings.TrimSpace(args)
+			if id == "" {
+				fmt.Println("❌ Usage: /accept <id>")
+				return nil
+			}
+			request, notice, err := files.accept(id)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return nil
+			}
+			fmt.Println(notice)
+			return out.WriteJSON(request)
+		}
+
+		if input == "/msg" || strings.HasPrefix(input, "/msg ") {
+			msg, err := parseDM(username, input)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return nil
+			}
+			return out.WriteJSON(msg)
+		}
+
+		if strings.HasPrefix(input, "/reply") {
+			msg, err := parseReply(roomKey, username, input)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return nil
+			}
+			return out.WriteJSON(msg)
+		}
+
+		ttl := 0
+		if strings.HasPrefix(input, "/burn") {
+			var err error
+			ttl, input, err = parseBurn(input)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return nil
+			}
+		}
+
+		msg, err := chatMessage(roomKey, username, input)
+		if err != nil {
+			fmt.Printf("❌ Encryption error: %v\n", err)
+			return nil
+		}
+		msg.TTL = ttl
+		return out.WriteJSON(msg)
+	}
+
+	// While reconnecting, input that goes to the server waits in pending
+	connected := true
+	var pending []string
+	reconnected := make(chan rejoined, 1)
+
+	// Downloads that stop receiving chunks ask for them again
+	retry := time.NewTicker(chunkTimeout / 2)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case err := <-lost:
+			if !shouldReconnect(err) {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					fmt.Printf("❌ Disconnected: %v\n", err)
+				}
+				return
+			}
+			connected = false
+			screen.print(fmt.Sprintf("🔌 Connection lost: %v", err))
+			go func() {
+				reconnected <- rejoin(ctx, dialer, u, session.Token, pin, encrypt, lastSeq.Load, screen.print)
+			}()
+
+		case r := <-reconnected:
+			if r.err != nil {
+				fmt.Printf("❌ Reconnect failed: %v\n", r.err)
+				return
+			}
+			if recreated(roomSalt, r.info, lastSeq.Load()) {
+				lastSeq.Store(0)
+				screen.forget()
+				screen.print("♻️  The room was recreated; message IDs start over")
+			}
+			roomKey, maxFileSize, roomSalt = r.key, r.info.MaxFileSize, r.info.Salt
+			files.useKey(r.key)
+			out.attach(r.conn)
+			go read(r.conn, r.key)
+			connected = true
+			screen.print(fmt.Sprintf("✅ Reconnected to room %s", pin))
+
+			queued := pending
+			pending = nil
+			if len(queued) > 0 {
+				screen.print(fmt.Sprintf("📤 Sending %d queued line(s)", len(queued)))
+			}
+			for _, input := range queued {
+				if err := send(input); err != nil {
+					pending = append(pending, input)
+				}
+			}
+
+		case now := <-retry.C:
+			if !connected {
+				continue
+			}
+			requests, notices := files.stalled(now)
+			for _, request := range requests {
+				if err := out.WriteJSON(request); err != nil {
+					screen.print(fmt.Sprintf("❌ Send error: %v; /accept %s again to resume", err, request.File.ID))
+				}
+			}
+			for _, notice := range notices {
+				screen.print(notice)
+			}
+
+		case input, ok := <-lines:
+			if !ok {
+				return
+			}
+			if input == "" {
+				continue
+			}
+			if input == "/quit" {
+				fmt.Println("👋 Goodbye!")
+				return
+			}
+
+			if input == "/help" {
+				fmt.Println("📋 Available commands:")
+				fmt.Println("  /quit - Exit the chat")
+				fmt.Println("  /who - List the members of the room")
+				fmt.Println("  /msg <username> <text> - Send a direct message to a user in any room (not end-to-end encrypted)")
+				fmt.Println("  /burn <seconds|duration> <text> - Send a self-destructing message")
+				fmt.Println("  /reply <id> <text> - Answer a message, starting or continuing its thread")
+				fmt.Println("  /thread <id> - Show only the thread a message belongs to")
+				fmt.Println("  /edit <id> <text> - Replace one of your messages (moderators: any message)")
+				fmt.Println("  /delete <id> - Retract one of your messages (moderators: any message)")
+				fmt.Println("  /send <path> - Offer a file to the room, encrypted like messages")
+				fmt.Println("  /accept <id> - Download an offered file into --download-dir")
+				fmt.Println("  /topic [text|clear] - Show or set the room topic (Room owner)")
+				fmt.Println("  /motd [line\\nline...|clear] - Show or set the message of the day (Room owner)")
+				fmt.Println("  /ttl [seconds|duration|off] - Show or set the room message timer (Room owner)")
+				fmt.Println("  /kick <username> - Kick a user (Room owner and moderators)")
+				fmt.Println("  /ban <username> [duration] [reason] - Ban a user from the room (Room owner and moderators)")
+				fmt.Println("  /unban <username> - Lift a room ban (Room owner and moderators)")
+				fmt.Println("  /bans - List bans (Room owner and moderators)")
+				fmt.Println("  /mute <username> [duration] - Stop a user sending messages (Room owner and moderators)")
+				fmt.Println("  /unmute <username> - Lift a mute (Room owner and moderators)")
+				fmt.Println("  /mod <username> - Make a member a moderator (Room owner)")
+				fmt.Println("  /unmod <username> - Revoke a moderator (Room owner)")
+				fmt.Println("  /owner <username> - Hand the room to another member (Room owner)")
+				if isAdmin {
+					fmt.Println("  /disable <username> - Disable an account (Admin only)")
+					fmt.Println("  /enable <username> - Re-enable an account (Admin only)")
+					fmt.Println("  /lock <username> <duration> - Lock an account, e.g. 12h (Admin only)")
+					fmt.Println("  /deluser <username> - Delete an account (Admin only)")
+					fmt.Println("  /ban -g <username> [duration] [reason] - Ban a user from every room (Admin only)")
+					fmt.Println("  /unban -g <username> - Lift a global ban (Admin only)")
+					fmt.Println("  /rooms [close <room>] - List active rooms, or close one (Admin only)")
+					fmt.Println("  /lockouts - Show failed logins and lockouts (Admin only)")
+					fmt.Println("  /unlock <username|address> - Clear failed logins and a lock (Admin only)")
+				}
+				fmt.Println("  /help - Show this help")
+				continue
+			}
+
+			if name, args, _ := strings.Cut(input, " "); name == "/thread" {
+				id, err := parseMessageID(strings.TrimSpace(args))
+				if err != nil {
+					fmt.Println("❌ Usage: /thread <id>")
+					continue
+				}
+				thread := screen.thread(id)
+				if len(thread) == 0 {
+					fmt.Printf("❌ Message #%d is not in this session's transcript.\n", id)
+					continue
+				}
+				fmt.Printf("🧵 Thread of #%d (%d messages):\n", id, len(thread))
+				for _, line := range thread {
+					fmt.Println("   " + line)
+				}
+				continue
+			}
+
+			if !connected {
+				if len(pending) >= maxPending {
+					fmt.Println("❌ Too much input is waiting for the reconnect; this line was dropped.")
+					continue
+				}
+				pending = append(pending, input)
+				fmt.Println("⏳ Not connected; this will be sent once the client reconnects.")
+				continue
+			}
+			if err := send(input); err != nil {
+				pending = append(pending, input)
+				fmt.Printf("⏳ Send failed (%v); this will be sent once the client reconnects.\n", err)
+			}
+		}
+	}
+}
+
+// changePassword logs in without joining a room and sets a new password as
+// part of the login handshake.
+func changePassword(serverURL, fingerprint string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return fmt.Errorf("invalid server URL: %w", err)
+	}
+
+	dialer, err := newDialer(u, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("🔑 Change Password")
+	fmt.Println("==================")
+	fmt.Println("Re-enter your current credentials first.")
+	username, password, err := auth.PromptCredentials()
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := dial(dialer, u, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := clientHandshake(conn, username, password, true); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Password for '%s' changed.\n", username)
+	return nil
+}
+
+// printToken logs in without joining a room and prints the session token,
+// which authenticates requests to the server's HTTP API.
+func printToken(serverURL, fingerprint string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return fmt.Errorf("invalid server URL: %w", err)
+	}
+
+	dialer, err := newDialer(u, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	username, password, err := auth.PromptCredentials()
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := dial(dialer, u, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	session, err := clientHandshake(conn, username, password, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Session token for '%s' (expires %s):\n%s\n", session.Username, session.ExpiresAt.Local().Format("2006-01-02 15:04"), session.Token)
+	return nil
+}
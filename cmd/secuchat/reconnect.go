@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	reconnectBase = time.Second
+	reconnectMax  = 30 * time.Second
+
+	// idleTimeout declares the connection dead; the server pings every 54s.
+	idleTimeout = 90 * time.Second
+
+	// maxPending bounds the input kept while reconnecting.
+	maxPending = 100
+)
+
+// backoff returns how long to wait before reconnect attempt n, counting from
+// zero. The delay doubles up to reconnectMax, and half of it is random so
+// clients dropped together do not all return at once.
+func backoff(attempt int) time.Duration {
+	delay := reconnectMax
+	if attempt < 5 {
+		delay = min(reconnectBase<<attempt, reconnectMax)
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// shouldReconnect reports whether a read error means the connection was
+// lost, rather than closed by the server on purpose, e.g. after a kick.
+func shouldReconnect(err error) bool {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code == websocket.CloseAbnormalClosure || closeErr.Code == websocket.CloseGoingAway
+	}
+	return true
+}
+
+// keepAlive makes reads fail once the server has been silent for
+// idleTimeout, answering its pings meanwhile, so half-open connections are
+// noticed and replaced.
+func keepAlive(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(idleTimeout))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeWait))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+}
+
+// rejoined is the outcome of reconnecting. info is the room_info frame.
+type rejoined struct {
+	conn *websocket.Conn
+	key  *e2e.RoomKey
+	info protocol.Message
+	err  error
+}
+
+// recreated reports whether the room joined with info is not the one whose
+// key salt was salt, because it emptied and was created again. Its message
+// numbers start over, so lastSeq and the IDs on screen no longer apply.
+func recreated(salt string, info protocol.Message, lastSeq uint64) bool {
+	return info.Salt != salt || info.Seq < lastSeq
+}
+
+// rejoin reconnects to the room with the session token until it succeeds,
+// ctx ends or the server refuses the session. It asks for the history after
+// lastSeq, even when that is 0, so messages sent while the client was away
+// are delivered and those already shown are not.
+func rejoin(ctx context.Context, dialer *websocket.Dialer, u *url.URL, token, pin string, encrypt bool, lastSeq func() uint64, notify func(string)) rejoined {
+	header := http.Header{"Authorization": {"Bearer " + token}}
+	for attempt := 0; ; attempt++ {
+		delay := backoff(attempt)
+		notify(fmt.Sprintf("🔄 Reconnecting in %s...", delay.Round(100*time.Millisecond)))
+		select {
+		case <-ctx.Done():
+			return rejoined{err: ctx.Err()}
+		case <-time.After(delay):
+		}
+
+		resume := *u
+		q := resume.Query()
+		q.Set("since", strconv.FormatUint(lastSeq(), 10))
+		resume.RawQuery = q.Encode()
+
+		conn, version, err := dial(dialer, &resume, header)
+		var refused *refusedError
+		if errors.As(err, &refused) && !refused.temporary() {
+			if refused.status == http.StatusUnauthorized {
+				return rejoined{err: fmt.Errorf("the server wants a new login: %v; run secuchat again to log in", refused)}
+			}
+			return rejoined{err: err}
+		}
+		if err != nil {
+			notify(fmt.Sprintf("⚠️  Reconnect failed: %v", err))
+			continue
+		}
+
+		key, info, err := joinRoom(conn, pin, encrypt, version)
+		if err != nil {
+			_ = conn.Close()
+			notify(fmt.Sprintf("⚠️  Rejoining the room failed: %v", err))
+			continue
+		}
+		return rejoined{conn: conn, key: key, info: info}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/gorilla/websocket"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{4, 16 * time.Second},
+		{5, reconnectMax},
+		{30, reconnectMax},
+		{100, reconnectMax},
+	}
+	for _, tt := range tests {
+		for range 50 {
+			got := backoff(tt.attempt)
+			if got < tt.max/2 || got > tt.max {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestShouldReconnect(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"dropped", &websocket.CloseError{Code: websocket.CloseAbnormalClosure}, true},
+		{"server going away", &websocket.CloseError{Code: websocket.CloseGoingAway}, true},
+		{"network error", io.ErrUnexpectedEOF, true},
+		{"timeout", errors.New("i/o timeout"), true},
+		{"normal close", &websocket.CloseError{Code: websocket.CloseNormalClosure}, false},
+		{"kicked", &websocket.CloseError{Code: websocket.ClosePolicyViolation, Text: "kicked"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldReconnect(tt.err); got != tt.want {
+				t.Fatalf("shouldReconnect(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecreated(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    protocol.Message
+		lastSeq uint64
+		want    bool
+	}{
+		{"same room", protocol.Message{Salt: "salt", Seq: 12}, 12, false},
+		{"messages missed", protocol.Message{Salt: "salt", Seq: 20}, 12, false},
+		{"nothing seen yet", protocol.Message{Salt: "salt"}, 0, false},
+		{"new salt", protocol.Message{Salt: "fresh", Seq: 20}, 12, true},
+		{"numbers went back", protocol.Message{Salt: "salt", Seq: 3}, 12, true},
+		{"emptied and recreated", protocol.Message{Salt: "salt"}, 12, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recreated("salt", tt.info, tt.lastSeq); got != tt.want {
+				t.Fatalf("recreated(%+v, %d) = %v, want %v", tt.info, tt.lastSeq, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// joinRoom waits for the room_info frame the server sends on join and, when
+// encryption is enabled, derives the room key from the PIN and room salt.
+// The frame must confirm the protocol version negotiated on connect. It is
+// returned too, for the room's file size cap, salt and newest message.
+func joinRoom(conn *websocket.Conn, pin string, encrypt bool, version int) (*e2e.RoomKey, protocol.Message, error) {
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var info protocol.Message
+	if err := conn.ReadJSON(&info); err != nil {
+		return nil, info, err
+	}
+	if info.Type == protocol.TypeRoomDenied {
+		return nil, info, fmt.Errorf("%s", info.Message)
+	}
+	if info.Type != protocol.TypeRoomInfo {
+		return nil, info, fmt.Errorf("unexpected server reply %q", info.Type)
+	}
+	if info.Version != 0 && info.Version != version {
+		return nil, info, fmt.Errorf("server confirmed protocol version %d, expected %d", info.Version, version)
+	}
+
+	if !encrypt {
+		return nil, info, nil
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(info.Salt)
+	if err != nil {
+		return nil, info, fmt.Errorf("invalid room salt")
+	}
+	key, err := e2e.NewRoomKey(pin, salt)
+	return key, info, err
+}
+
+// serverCommands are handled by the server rather than relayed, so they are
+// sent in plaintext even in encrypted rooms.
+var serverCommands = map[string]bool{
+	"/who":      true,
+	"/kick":     true,
+	"/ban":      true,
+	"/unban":    true,
+	"/bans":     true,
+	"/mute":     true,
+	"/unmute":   true,
+	"/mod":      true,
+	"/unmod":    true,
+	"/owner":    true,
+	"/ttl":      true,
+	"/topic":    true,
+	"/motd":     true,
+	"/disable":  true,
+	"/enable":   true,
+	"/lock":     true,
+	"/deluser":  true,
+	"/lockouts": true,
+	"/rooms":    true,
+	"/unlock":   true,
+}
+
+func isServerCommand(input string) bool {
+	name, _, _ := strings.Cut(input, " ")
+	return serverCommands[name]
+}
+
+// parseBurn splits "/burn <ttl> <text>" into its timer and text.
+func parseBurn(input string) (int, string, error) {
+	fields := strings.SplitN(input, " ", 3)
+	if fields[0] != "/burn" || len(fields) < 3 || strings.TrimSpace(fields[2]) == "" {
+		return 0, "", fmt.Errorf("usage: /burn <seconds|duration> <text>")
+	}
+	ttl, err := protocol.ParseTTL(fields[1])
+	if err != nil {
+		return 0, "", err
+	}
+	if ttl == 0 {
+		return 0, "", fmt.Errorf("a burn timer must be at least 1s")
+	}
+	return ttl, fields[2], nil
+}
+
+// parseDM splits "/msg <user> <text>" into a direct message frame. DMs are
+// addressed to one user in any room, so they cannot use the room key and
+// travel in plaintext over the connection.
+func parseDM(username, input string) (protocol.Message, error) {
+	fields := strings.SplitN(input, " ", 3)
+	if fields[0] != "/msg" || len(fields) < 3 || strings.TrimSpace(fields[2]) == "" {
+		return protocol.Message{}, fmt.Errorf("usage: /msg <username> <text>")
+	}
+	return protocol.Message{
+		Type:      protocol.TypeDM,
+		Message:   fields[2],
+		Username:  username,
+		To:        fields[1],
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// formatDM renders a direct message so it stands apart from room traffic.
+func formatDM(self string, msg protocol.Message) string {
+	timestamp := ""
+	if t, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
+		timestamp = "[" + t.Format("15:04") + "] "
+	}
+	if msg.Username == self {
+		return fmt.Sprintf("✉️  %s[DM to %s] %s", timestamp, msg.To, msg.Message)
+	}
+	return fmt.Sprintf("✉️  %s[DM from %s] %s", timestamp, msg.Username, msg.Message)
+}
+
+// describeMember renders one room member as "name (Display Name) [ADMIN]".
+func describeMember(m protocol.Member) string {
+	text := m.Username
+	if m.DisplayName != "" && m.DisplayName != m.Username {
+		text += " (" + m.DisplayName + ")"
+	}
+	if m.Admin {
+		text += " [ADMIN]"
+	}
+	return text
+}
+
+// formatRoster renders the reply to /who.
+func formatRoster(msg protocol.Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "👥 %d in room %s:", len(msg.Members), msg.Message)
+	for _, m := range msg.Members {
+		joined := m.JoinedAt
+		if t, err := time.Parse(time.RFC3339, m.JoinedAt); err == nil {
+			joined = t.Format("Jan 2 15:04")
+		}
+		fmt.Fprintf(&b, "\n   • %s - %s - joined %s", describeMember(m), m.Role, joined)
+	}
+	return b.String()
+}
+
+// formatPresence renders a member joining or leaving the room.
+func formatPresence(msg protocol.Message) string {
+	if len(msg.Members) == 0 {
+		return ""
+	}
+	who := describeMember(msg.Members[0])
+	if msg.Event == protocol.PresenceLeave {
+		return fmt.Sprintf("🚪 %s left the room", who)
+	}
+	return fmt.Sprintf("👋 %s joined the room", who)
+}
+
+// chatMessage builds an outgoing chat frame, sealing the body when the room
+// is encrypted.
+func chatMessage(key *e2e.RoomKey, username, text string) (protocol.Message, error) {
+	msg := protocol.Message{
+		Type:      protocol.TypeMessage,
+		Message:   text,
+		Username:  username,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if key == nil {
+		return msg, nil
+	}
+
+	sealed, err := key.Seal(username, []byte(text))
+	if err != nil {
+		return msg, err
+	}
+	msg.Message = sealed
+	msg.Enc = e2e.Algorithm
+	return msg, nil
+}
+
+// openChatMessage returns the displayable body of a received chat frame and
+// a warning tag when it is not an authenticated end-to-end message.
+func openChatMessage(key *e2e.RoomKey, msg protocol.Message) (string, string) {
+	switch {
+	case msg.Enc == "" && key == nil:
+		return msg.Message, ""
+	case msg.Enc == "":
+		return msg.Message, "⚠️ [UNENCRYPTED] "
+	case key == nil:
+		return "(encrypted message; end-to-end encryption is disabled)", "🔐 "
+	case msg.Enc != e2e.Algorithm:
+		return fmt.Sprintf("(unsupported cipher %q)", msg.Enc), "⚠️ [UNVERIFIED] "
+	}
+
+	// Edits are sealed by whoever made them
+	signer := msg.Username
+	if msg.Edited && msg.By != "" {
+		signer = msg.By
+	}
+	plaintext, err := key.Open(signer, msg.Message)
+	if err != nil {
+		return fmt.Sprintf("(%v — wrong PIN or tampered message)", err), "⚠️ [UNVERIFIED] "
+	}
+	return string(plaintext), ""
+}
+
+// formatChat renders a chat message, or an edit of one, as a transcript
+// line. The message ID is shown so it can be edited, deleted or replied to.
+func formatChat(key *e2e.RoomKey, msg protocol.Message) string {
+	text, warning := openChatMessage(key, msg)
+	if msg.History {
+		warning = "📜 " + warning
+	}
+	if msg.TTL > 0 {
+		warning = "🔥 " + warning
+		text = fmt.Sprintf("%s (burns in %s)", text, time.Duration(msg.TTL)*time.Second)
+	}
+	if msg.Edited {
+		if msg.By == "" || msg.By == msg.Username {
+			text += " (edited)"
+		} else {
+			text += " (edited by " + msg.By + ")"
+		}
+	}
+
+	id := ""
+	if msg.Seq > 0 {
+		id = fmt.Sprintf("#%d ", msg.Seq)
+	}
+	if msg.ReplyTo > 0 {
+		text = fmt.Sprintf("↪ #%d %s", msg.ReplyTo, text)
+	}
+	if t, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
+		return fmt.Sprintf("%s[%s] %s%s: %s", warning, t.Format("15:04"), id, msg.Username, text)
+	}
+	return fmt.Sprintf("%s%s%s: %s", warning, id, msg.Username, text)
+}
+
+// formatDeleted replaces a retracted message in the transcript.
+func formatDeleted(msg protocol.Message) string {
+	if msg.By == "" || msg.By == msg.Username {
+		return fmt.Sprintf("🗑️ #%d message deleted by %s", msg.Seq, msg.Username)
+	}
+	return fmt.Sprintf("🗑️ #%d message from %s deleted by %s", msg.Seq, msg.Username, msg.By)
+}
+
+// parseMessageID reads a message ID as shown in the transcript, "#12" or "12".
+func parseMessageID(value string) (uint64, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(value, "#"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid message ID %q", value)
+	}
+	return id, nil
+}
+
+// parseEdit builds the frame for "/edit <id> <text>", sealing the new body
+// like any chat message.
+func parseEdit(key *e2e.RoomKey, username, input string) (protocol.Message, error) {
+	fields := strings.SplitN(input, " ", 3)
+	if fields[0] != "/edit" || len(fields) < 3 || strings.TrimSpace(fields[2]) == "" {
+		return protocol.Message{}, fmt.Errorf("usage: /edit <id> <text>")
+	}
+	id, err := parseMessageID(fields[1])
+	if err != nil {
+		return protocol.Message{}, err
+	}
+	msg, err := chatMessage(key, username, fields[2])
+	if err != nil {
+		return protocol.Message{}, err
+	}
+	msg.Type = protocol.TypeEdit
+	msg.Seq = id
+	return msg, nil
+}
+
+// parseDelete builds the frame for "/delete <id>".
+func parseDelete(input string) (protocol.Message, error) {
+	fields := strings.Fields(input)
+	if fields[0] != "/delete" || len(fields) != 2 {
+		return protocol.Message{}, fmt.Errorf("usage: /delete <id>")
+	}
+	id, err := parseMessageID(fields[1])
+	if err != nil {
+		return protocol.Message{}, err
+	}
+	return protocol.Message{Type: protocol.TypeDelete, Seq: id}, nil
+}
+
+// parseReply builds the frame for "/reply <id> <text>", a chat message that
+// answers message id.
+func parseReply(key *e2e.RoomKey, username, input string) (protocol.Message, error) {
+	fields := strings.SplitN(input, " ", 3)
+	if fields[0] != "/reply" || len(fields) < 3 || strings.TrimSpace(fields[2]) == "" {
+		return protocol.Message{}, fmt.Errorf("usage: /reply <id> <text>")
+	}
+	id, err := parseMessageID(fields[1])
+	if err != nil {
+		return protocol.Message{}, err
+	}
+	msg, err := chatMessage(key, username, fields[2])
+	if err != nil {
+		return protocol.Message{}, err
+	}
+	msg.ReplyTo = id
+	return msg, nil
+}
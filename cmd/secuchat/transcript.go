@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+)
+
+// maxTranscriptLines bounds the scrollback kept for redraws.
+const maxTranscriptLines = 1000
+
+// clearScreen wipes the terminal and its scrollback, so burned messages do
+// not survive above the redrawn transcript.
+const clearScreen = "\033[2J\033[3J\033[H"
+
+// transcript is the chat scrollback. The client only keeps it in memory and
+// redraws the screen from it when self-destructing messages expire.
+type transcript struct {
+	mu    sync.Mutex
+	lines []transcriptLine
+}
+
+type transcriptLine struct {
+	text    string
+	expires time.Time
+
+	// seq is the ID of the chat message shown, so edits can find it, and
+	// replyTo the message it answers.
+	seq     uint64
+	replyTo uint64
+}
+
+// print shows a line that stays on screen.
+func (t *transcript) print(text string) {
+	t.add(text, 0)
+}
+
+// add shows a line and, with a positive ttl in seconds, schedules it to be
+// removed again.
+func (t *transcript) add(text string, ttl int) {
+	t.addMessage(protocol.Message{TTL: ttl}, text)
+}
+
+// addMessage shows chat message msg rendered as text, burning it when it
+// has a TTL.
+func (t *transcript) addMessage(msg protocol.Message, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	line := transcriptLine{text: text, seq: msg.Seq, replyTo: msg.ReplyTo}
+	if msg.TTL > 0 {
+		line.expires = time.Now().Add(time.Duration(msg.TTL) * time.Second)
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > maxTranscriptLines {
+		t.lines = t.lines[len(t.lines)-maxTranscriptLines:]
+	}
+	fmt.Println(text)
+}
+
+// replace swaps the text of message seq and redraws the screen, so the old
+// text is gone from the scrollback too. Messages no longer on screen are
+// ignored.
+func (t *transcript) replace(seq uint64, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	found := false
+	for i := range t.lines {
+		if seq != 0 && t.lines[i].seq == seq {
+			t.lines[i].text = text
+			found = true
+		}
+	}
+	if found {
+		t.redraw()
+	}
+}
+
+// forget drops the message IDs of every line, so edits, deletes and
+// threads from a recreated room, which numbers its messages afresh, do not
+// touch lines from before.
+func (t *transcript) forget() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.lines {
+		t.lines[i].seq = 0
+		t.lines[i].replyTo = 0
+	}
+}
+
+// thread returns the lines of the thread message seq belongs to: its root
+// and every reply below it, indented by depth, in transcript order.
+func (t *transcript) thread(seq uint64) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent := make(map[uint64]uint64)
+	for _, line := range t.lines {
+		if line.seq != 0 {
+			parent[line.seq] = line.replyTo
+		}
+	}
+	if _, ok := parent[seq]; !ok {
+		return nil
+	}
+
+	// ancestry walks up from seq to the oldest message still on screen,
+	// returning that root and how many replies deep seq is
+	ancestry := func(seq uint64) (uint64, int) {
+		depth := 0
+		for depth < len(parent) {
+			up, ok := parent[seq]
+			if _, onScreen := parent[up]; !ok || up == 0 || !onScreen {
+				break
+			}
+			seq = up
+			depth++
+		}
+		return seq, depth
+	}
+
+	root, _ := ancestry(seq)
+	var lines []string
+	for _, line := range t.lines {
+		if line.seq == 0 {
+			continue
+		}
+		if top, depth := ancestry(line.seq); top == root {
+			lines = append(lines, strings.Repeat("  ", depth)+line.text)
+		}
+	}
+	return lines
+}
+
+// redraw clears the terminal and prints the transcript again. Callers hold
+// t.mu.
+func (t *transcript) redraw() {
+	fmt.Print(clearScreen)
+	for _, line := range t.lines {
+		fmt.Println(line.text)
+	}
+}
+
+// burn drops expired lines and redraws the screen if there were any.
+func (t *transcript) burn(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kept := t.lines[:0]
+	burned := 0
+	for _, line := range t.lines {
+		if !line.expires.IsZero() && !now.Before(line.expires) {
+			burned++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	// Clear the dropped tail so burned text does not linger in memory
+	for i := len(kept); i < len(t.lines); i++ {
+		t.lines[i] = transcriptLine{}
+	}
+	t.lines = kept
+	if burned == 0 {
+		return
+	}
+
+	t.redraw()
+	fmt.Printf("🔥 %d message(s) self-destructed\n", burned)
+}
+
+// expireLoop burns expired lines once a second until ctx is done.
+func (t *transcript) expireLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.burn(now)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package e2e implements client-side encryption of room messages. Keys are
+// derived from the room PIN, which never leaves the client: the server only
+// sees a derived room ID, the per-room salt it hands out, and ciphertext.
+package e2e
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Algorithm names the cipher in protocol.Message.Enc.
+const Algorithm = "xchacha20poly1305"
+
+const (
+	SaltSize   = 16
+	roomIDSize = 16
+)
+
+// roomIDSalt is fixed so every client maps the same PIN to the same room.
+var roomIDSalt = []byte("secuchat/room-id/v1")
+
+// RoomID derives the identifier a client presents to the server instead of
+// the PIN. It is deliberately slow to make offline PIN guessing expensive.
+func RoomID(pin string) string {
+	return hex.EncodeToString(argon2.IDKey([]byte(pin), roomIDSalt, 1, 64*1024, 4, roomIDSize))
+}
+
+// NewSalt returns a random per-room key salt. The server generates it when a
+// room is created and hands it to every member.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// RoomKey seals and opens message bodies for one room.
+type RoomKey struct {
+	aead   cipher.AEAD
+	roomID string
+}
+
+// NewRoomKey derives the room key from the PIN and the server-issued salt.
+func NewRoomKey(pin string, salt []byte) (*RoomKey, error) {
+	if len(salt) < SaltSize {
+		return nil, fmt.Errorf("room salt too short")
+	}
+	key := argon2.IDKey([]byte(pin), salt, 1, 64*1024, 4, chacha20poly1305.KeySize)
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	return &RoomKey{aead: aead, roomID: RoomID(pin)}, nil
+}
+
+// additionalData binds a ciphertext to its room and sender, so the server
+// cannot replay it into another room or attribute it to someone else.
+func (k *RoomKey) additionalData(sender string) []byte {
+	return []byte(k.roomID + "\x00" + sender)
+}
+
+// Seal encrypts plaintext from sender and returns base64(nonce || ciphertext).
+func (k *RoomKey) Seal(sender string, plaintext []byte) (string, error) {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(plaintext)+k.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := k.aead.Seal(nonce, nonce, plaintext, k.additionalData(sender))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts and authenticates a body produced by Seal for sender.
+func (k *RoomKey) Open(sender, sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("malformed ciphertext")
+	}
+	if len(data) < k.aead.NonceSize()+k.aead.Overhead() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:k.aead.NonceSize()], data[k.aead.NonceSize():]
+	plaintext, err := k.aead.Open(nil, nonce, ciphertext, k.additionalData(sender))
+	if err != nil {
+		return nil, fmt.Errorf("message failed authentication")
+	}
+	return plaintext, nil
+}
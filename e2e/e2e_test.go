@@ -0,0 +1,60 @@
+package e2e
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	salt := bytes.Repeat([]byte{7}, SaltSize)
+	key, err := NewRoomKey("REDTEAM01", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPIN, err := NewRoomKey("BLUETEAM01", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSalt, err := NewRoomKey("REDTEAM01", bytes.Repeat([]byte{8}, SaltSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("meet at the usual place")
+	sealed, err := key.Seal("alice", plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     *RoomKey
+		sender  string
+		sealed  string
+		wantErr bool
+	}{
+		{"same sender", key, "alice", sealed, false},
+		{"wrong sender", key, "mallory", sealed, true},
+		{"wrong PIN", otherPIN, "alice", sealed, true},
+		{"wrong salt", otherSalt, "alice", sealed, true},
+		{"tampered", key, "alice", sealed[:len(sealed)-4] + "AAAA", true},
+		{"truncated", key, "alice", sealed[:16], true},
+		{"not base64", key, "alice", "not base64!", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.key.Open(tt.sender, tt.sealed)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Open() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("Open() = %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
@@ -1,11 +1,11 @@
 module github.com/EJ-Edwards/Secuchat-CLI
 
-go 1.23
+go 1.24.0
 
 require (
-    github.com/gorilla/websocket v1.5.3
-    golang.org/x/crypto v0.47.0
-    golang.org/x/term v0.39.0
+	github.com/gorilla/websocket v1.5.3
+	golang.org/x/crypto v0.47.0
+	golang.org/x/term v0.39.0
 )
 
-require golang.org/x/sys v0.40.0 
\ No newline at end of file
+require golang.org/x/sys v0.40.0
@@ -0,0 +1,324 @@
+// Package history keeps an encrypted, append-only log of the chat messages
+// relayed in each room, so members who join later can catch up.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/vault"
+)
+
+// maxRecordSize bounds one sealed line; chat frames are far smaller.
+const maxRecordSize = 1 << 20
+
+// Store holds the room logs in one directory, sealed with the server vault.
+type Store struct {
+	dir   string
+	vault *vault.Vault
+}
+
+// NewStore uses dir for room logs, creating it if needed.
+func NewStore(dir string, v *vault.Vault) (*Store, error) {
+	if v == nil {
+		return nil, fmt.Errorf("history requires a database key")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, vault: v}, nil
+}
+
+// record is one line of a log. The first line of every log carries the
+// room's key salt, so end-to-end encrypted history stays readable after the
+// room is recreated; every later line carries one message, or only the
+// sequence number of a message that must not be stored.
+type record struct {
+	Salt    []byte            `json:"salt,omitempty"`
+	Message *protocol.Message `json:"message,omitempty"`
+	Seq     uint64            `json:"seq,omitempty"`
+}
+
+// Log is the history of one room. It is not safe for concurrent use; each
+// hub owns the log of its room.
+type Log struct {
+	store   *Store
+	roomID  string
+	file    *os.File
+	salt    []byte
+	lines   int
+	size    int64
+	lastSeq uint64
+
+	// index locates each stored message by sequence number, so looking one
+	// up decrypts a single line and a missing one is not searched for.
+	index map[uint64]position
+}
+
+// position is where a line starts in the log file.
+type position struct {
+	line   int
+	offset int64
+}
+
+// fileName hashes the room ID so arbitrary PINs of ad-hoc rooms map to safe
+// names that do not reveal them.
+func (s *Store) fileName(roomID string) string {
+	sum := sha256.Sum256([]byte(roomID))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+".log")
+}
+
+// label binds each sealed line to its room and position, so lines cannot be
+// moved between logs or reordered.
+func (l *Log) label(line int) string {
+	return fmt.Sprintf("secuchat/history/%s/%d", l.roomID, line)
+}
+
+// opening serializes Open so two hubs racing for a new room do not both
+// write a header.
+var opening sync.Mutex
+
+// Open loads the log of roomID, creating it with a fresh room salt if it
+// does not exist yet.
+func (s *Store) Open(roomID string) (*Log, error) {
+	opening.Lock()
+	defer opening.Unlock()
+
+	file, err := os.OpenFile(s.fileName(roomID), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{store: s, roomID: roomID, file: file, index: make(map[uint64]position)}
+
+	err = l.scan(func(at position, rec record) error {
+		if at.line == 0 {
+			l.salt = rec.Salt
+		} else if rec.Message != nil {
+			l.lastSeq = rec.Message.Seq
+			l.index[rec.Message.Seq] = at
+		} else if rec.Seq > 0 {
+			l.lastSeq = rec.Seq
+		}
+		return nil
+	})
+	if err == nil && l.lines == 0 {
+		l.salt, err = e2e.NewSalt()
+		if err == nil {
+			err = l.write(record{Salt: l.salt})
+		}
+	}
+	if err == nil && len(l.salt) < e2e.SaltSize {
+		err = fmt.Errorf("history for room is missing its salt")
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// scan decrypts every line in order, counting them into l.lines and their
+// bytes into l.size.
+func (l *Log) scan(fn func(at position, rec record) error) error {
+	if _, err := l.file.Seek(0, 0); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(l.file)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+
+	at := position{}
+	for scanner.Scan() {
+		rec, err := l.open(at.line, scanner.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := fn(at, rec); err != nil {
+			return err
+		}
+		at.line++
+		at.offset += int64(len(scanner.Bytes())) + 1
+	}
+	l.lines = at.line
+	l.size = at.offset
+	return scanner.Err()
+}
+
+// open decrypts one sealed line.
+func (l *Log) open(line int, sealed []byte) (record, error) {
+	var rec record
+	plaintext, err := l.store.vault.Open(sealed, l.label(line))
+	if err != nil {
+		return rec, fmt.Errorf("history line %d: %w", line+1, err)
+	}
+	if err := json.Unmarshal(plaintext, &rec); err != nil {
+		return rec, fmt.Errorf("history line %d: %w", line+1, err)
+	}
+	return rec, nil
+}
+
+// seal encrypts rec as the given line, newline included.
+func (l *Log) seal(line int, rec record) ([]byte, error) {
+	plaintext, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := l.store.vault.Seal(plaintext, l.label(line))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, sealed); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func (l *Log) write(rec record) error {
+	line, err := l.seal(l.lines, rec)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(line); err != nil {
+		return err
+	}
+	if rec.Message != nil {
+		l.index[rec.Message.Seq] = position{line: l.lines, offset: l.size}
+	}
+	l.lines++
+	l.size += int64(len(line))
+	return nil
+}
+
+// Salt returns the room key salt recorded for the room.
+func (l *Log) Salt() []byte {
+	return l.salt
+}
+
+// LastSeq returns the sequence number of the newest stored message.
+func (l *Log) LastSeq() uint64 {
+	return l.lastSeq
+}
+
+// Append stores one relayed message.
+func (l *Log) Append(msg protocol.Message) error {
+	if err := l.write(record{Message: &msg}); err != nil {
+		return err
+	}
+	l.lastSeq = msg.Seq
+	return nil
+}
+
+// Skip records that seq was used without storing its message, so sequence
+// numbers keep increasing after the room is recreated.
+func (l *Log) Skip(seq uint64) error {
+	if err := l.write(record{Seq: seq}); err != nil {
+		return err
+	}
+	l.lastSeq = seq
+	return nil
+}
+
+// Since returns up to limit messages with a sequence number above seq,
+// newest last. With seq 0 it returns the last limit messages. The count
+// reports how many older matching messages were left out.
+func (l *Log) Since(seq uint64, limit int) ([]protocol.Message, int, error) {
+	var messages []protocol.Message
+	err := l.scan(func(_ position, rec record) error {
+		if rec.Message != nil && rec.Message.Seq > seq {
+			messages = append(messages, *rec.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(messages) > limit {
+		return messages[len(messages)-limit:], len(messages) - limit, nil
+	}
+	return messages, 0, nil
+}
+
+// Find returns the stored message with sequence number seq, reading only
+// its line.
+func (l *Log) Find(seq uint64) (protocol.Message, bool, error) {
+	at, ok := l.index[seq]
+	if !ok {
+		return protocol.Message{}, false, nil
+	}
+	reader := bufio.NewReader(io.NewSectionReader(l.file, at.offset, maxRecordSize+1))
+	sealed, err := reader.ReadBytes('\n')
+	if err != nil {
+		return protocol.Message{}, false, fmt.Errorf("history line %d: %w", at.line+1, err)
+	}
+	rec, err := l.open(at.line, bytes.TrimSuffix(sealed, []byte("\n")))
+	if err != nil {
+		return protocol.Message{}, false, err
+	}
+	if rec.Message == nil || rec.Message.Seq != seq {
+		return protocol.Message{}, false, fmt.Errorf("history line %d: index out of date", at.line+1)
+	}
+	return *rec.Message, true, nil
+}
+
+// Replace swaps the stored message seq for msg, or with a nil msg deletes
+// it, keeping only its sequence number. The whole log is resealed into a
+// new file, so the old text does not survive on disk. It reports whether
+// seq was stored.
+func (l *Log) Replace(seq uint64, msg *protocol.Message) (bool, error) {
+	if _, ok := l.index[seq]; !ok {
+		return false, nil
+	}
+
+	var rewritten bytes.Buffer
+	index := make(map[uint64]position, len(l.index))
+	err := l.scan(func(at position, rec record) error {
+		if rec.Message != nil && rec.Message.Seq == seq {
+			rec = record{Seq: seq}
+			if msg != nil {
+				rec = record{Message: msg}
+			}
+		}
+		if rec.Message != nil {
+			index[rec.Message.Seq] = position{line: at.line, offset: int64(rewritten.Len())}
+		}
+		sealed, err := l.seal(at.line, rec)
+		if err != nil {
+			return err
+		}
+		rewritten.Write(sealed)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	path := l.store.fileName(l.roomID)
+	if err := vault.WriteFileAtomic(path, rewritten.Bytes(), 0600); err != nil {
+		return false, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return false, err
+	}
+	_ = l.file.Close()
+	l.file = file
+	l.index = index
+	l.size = int64(rewritten.Len())
+	return true, nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	return l.file.Close()
+}
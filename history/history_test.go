@@ -0,0 +1,138 @@
+package history
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/vault"
+)
+
+func TestFindAfterReplace(t *testing.T) {
+	v, err := vault.New([]byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(t.TempDir(), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := store.Open("REDTEAM01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for seq := uint64(1); seq <= 4; seq++ {
+		if seq == 3 {
+			err = l.Skip(seq)
+		} else {
+			err = l.Append(protocol.Message{Type: protocol.TypeMessage, Seq: seq, Message: "message"})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A longer body moves every later line
+	if _, err := l.Replace(2, &protocol.Message{Type: protocol.TypeMessage, Seq: 2, Message: strings.Repeat("edited ", 100)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Replace(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := l.Replace(9, nil); found || err != nil {
+		t.Fatalf("Replace(9) = %v, %v; want false, nil", found, err)
+	}
+	if err := l.Append(protocol.Message{Type: protocol.TypeMessage, Seq: 5, Message: "appended"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		seq       uint64
+		wantFound bool
+		wantText  string
+	}{
+		{1, false, ""},
+		{2, true, strings.Repeat("edited ", 100)},
+		{3, false, ""},
+		{4, true, "message"},
+		{5, true, "appended"},
+		{6, false, ""},
+	}
+	check := func(log *Log) {
+		t.Helper()
+		for _, tt := range tests {
+			msg, found, err := log.Find(tt.seq)
+			if err != nil {
+				t.Fatalf("Find(%d) error = %v", tt.seq, err)
+			}
+			if found != tt.wantFound || msg.Message != tt.wantText {
+				t.Fatalf("Find(%d) = %q, %v; want %q, %v", tt.seq, msg.Message, found, tt.wantText, tt.wantFound)
+			}
+		}
+	}
+	check(l)
+
+	// Reopening rebuilds the index from the rewritten file
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := store.Open("REDTEAM01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	check(reopened)
+}
+
+func TestSince(t *testing.T) {
+	v, err := vault.New([]byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(t.TempDir(), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := store.Open("REDTEAM01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for seq := uint64(1); seq <= 6; seq++ {
+		if seq == 3 {
+			err = l.Skip(seq)
+		} else {
+			err = l.Append(protocol.Message{Type: protocol.TypeMessage, Seq: seq, Message: "message"})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		seq         uint64
+		limit       int
+		wantFirst   uint64
+		wantCount   int
+		wantSkipped int
+	}{
+		{"latest", 0, 2, 5, 2, 3},
+		{"everything", 0, 10, 1, 5, 0},
+		{"resume", 2, 10, 4, 3, 0},
+		{"resume past the limit", 1, 2, 5, 2, 2},
+		{"caught up", 6, 10, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, skipped, err := l.Since(tt.seq, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != tt.wantCount || skipped != tt.wantSkipped {
+				t.Fatalf("Since(%d, %d) = %d messages, %d skipped; want %d, %d", tt.seq, tt.limit, len(messages), skipped, tt.wantCount, tt.wantSkipped)
+			}
+			if len(messages) > 0 && messages[0].Seq != tt.wantFirst {
+				t.Fatalf("Since(%d, %d) starts at %d, want %d", tt.seq, tt.limit, messages[0].Seq, tt.wantFirst)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package hub
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+var (
+	errRoomNotFound = errors.New("no such room")
+	errRoomDenied   = errors.New("access denied")
+	errRoomBanned   = errors.New("banned")
+)
+
+// authorize decides whether session may join the room the client asked for
+// with pin. Ad-hoc rooms come back as an undefined Room keyed by the PIN.
+func (m *HubManager) authorize(pin string, session auth.Session) (rooms.Room, bool, error) {
+	db, err := rooms.Load()
+	if err != nil {
+		return rooms.Room{}, false, err
+	}
+
+	room, defined := db.Lookup(pin)
+	if !defined {
+		if !m.opts.OpenRooms {
+			return rooms.Room{}, false, fmt.Errorf("%w; ask an admin to define it", errRoomNotFound)
+		}
+		room = rooms.Room{ID: pin, Name: pin}
+	}
+
+	if ban, banned := db.Banned(room.ID, session.Username, time.Now()); banned {
+		return rooms.Room{}, false, fmt.Errorf("%w by %s %s", errRoomBanned, ban.By, ban.Terms())
+	}
+	if !defined {
+		return room, false, nil
+	}
+
+	user, err := auth.CheckAccount(session.Username)
+	if err != nil {
+		return rooms.Room{}, false, fmt.Errorf("%w: %v", errRoomDenied, err)
+	}
+	if !room.Allows(session.Username, user.Groups, user.IsAdmin) {
+		return rooms.Room{}, false, fmt.Errorf("%w: you are not on the access list for room '%s'", errRoomDenied, room.Name)
+	}
+	return room, true, nil
+}
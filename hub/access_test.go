@@ -0,0 +1,59 @@
+package hub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+func TestAuthorize(t *testing.T) {
+	users := map[string]auth.User{
+		"root":  {IsAdmin: true},
+		"alice": {Groups: []string{"red"}},
+		"bob":   {},
+		"carol": {Groups: []string{"blue"}},
+	}
+
+	tests := []struct {
+		name        string
+		openRooms   bool
+		pin         string
+		username    string
+		wantName    string
+		wantDefined bool
+		wantErr     error
+	}{
+		{"listed user", false, "1234", "bob", "ops", true, nil},
+		{"listed group", false, "1234", "alice", "ops", true, nil},
+		{"by room ID", false, e2e.RoomID("1234"), "alice", "ops", true, nil},
+		{"not on the list", false, "1234", "carol", "", false, errRoomDenied},
+		{"admin override", false, "1234", "root", "ops", true, nil},
+		{"unknown account", false, "1234", "mallory", "", false, errRoomDenied},
+		{"open room", false, "5678", "carol", "lobby", true, nil},
+		{"undefined room", false, "9999", "carol", "", false, errRoomNotFound},
+		{"ad-hoc room", true, "9999", "carol", "9999", false, nil},
+		{"open rooms keep access lists", true, "1234", "carol", "", false, errRoomDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _ := testServer(t, users, Options{OpenRooms: tt.openRooms})
+			if err := rooms.CreateRoom("root", true, "ops", "1234", []string{"bob"}, []string{"red"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := rooms.CreateRoom("root", true, "lobby", "5678", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			room, defined, err := manager.authorize(tt.pin, auth.Session{Username: tt.username})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authorize() error = %v, want %v", err, tt.wantErr)
+			}
+			if room.Name != tt.wantName || defined != tt.wantDefined {
+				t.Fatalf("authorize() = %q, %v, want %q, %v", room.Name, defined, tt.wantName, tt.wantDefined)
+			}
+		})
+	}
+}
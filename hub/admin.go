@@ -0,0 +1,182 @@
+package hub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+)
+
+// roomStatus is what global admins see about an active room.
+type roomStatus struct {
+	Alias        string    `json:"alias"`
+	Defined      bool      `json:"defined"`
+	Members      int       `json:"members"`
+	Connections  int       `json:"connections"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// roomAlias names a room for admins. Defined rooms go by their name; ad-hoc
+// rooms by a hash, since their ID may be a raw PIN.
+func roomAlias(id, name string, defined bool) string {
+	if defined {
+		return name
+	}
+	sum := sha256.Sum256([]byte(id))
+	return "adhoc-" + hex.EncodeToString(sum[:4])
+}
+
+// touch refreshes the room's status after clients come and go, and marks
+// activity when active is set. Only called from run.
+func (h *Hub) touch(active bool) {
+	users := make(map[string]bool)
+	for client := range h.clients {
+		users[client.username] = true
+	}
+
+	h.statusMu.Lock()
+	defer h.statusMu.Unlock()
+	h.status.Members = len(users)
+	h.status.Connections = len(h.clients)
+	if active {
+		h.status.LastActivity = time.Now().UTC()
+	}
+}
+
+// roomStatuses reports every active room, sorted by alias.
+func (m *HubManager) roomStatuses() []roomStatus {
+	m.mu.Lock()
+	statuses := make([]roomStatus, 0, len(m.hubs))
+	for _, h := range m.hubs {
+		h.statusMu.Lock()
+		statuses = append(statuses, h.status)
+		h.statusMu.Unlock()
+	}
+	m.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Alias < statuses[j].Alias })
+	return statuses
+}
+
+// closeRoom disconnects everyone from the active room called alias. It does
+// not wait, so it is safe to call from a hub's own goroutine.
+func (m *HubManager) closeRoom(alias, admin string) bool {
+	var target *Hub
+	m.mu.Lock()
+	for _, h := range m.hubs {
+		if h.alias == alias {
+			target = h
+			break
+		}
+	}
+	m.mu.Unlock()
+	if target == nil {
+		return false
+	}
+
+	log.Printf("Room %s closed by admin %s", target.name, admin)
+	notice := fmt.Sprintf("🚪 Room %s was closed by admin %s.", target.name, admin)
+	go func() {
+		select {
+		case target.exec <- func() {
+			target.closing = true
+			for client := range target.clients {
+				target.reply(client, notice)
+				target.removeClient(client)
+			}
+		}:
+		case <-target.done:
+		}
+	}()
+	return true
+}
+
+// listRooms handles /rooms and /rooms close <alias> for global admins.
+func (h *Hub) listRooms(client *Client, args string) {
+	if !client.isAdmin {
+		h.reply(client, "❌ Access denied. Admin privileges required.")
+		return
+	}
+
+	if args != "" {
+		alias, ok := strings.CutPrefix(args, "close ")
+		alias = strings.TrimSpace(alias)
+		if !ok || alias == "" {
+			h.reply(client, "❌ Usage: /rooms [close <room>]")
+			return
+		}
+		if !h.manager.closeRoom(alias, client.username) {
+			h.reply(client, fmt.Sprintf("❌ No active room '%s'.", alias))
+			return
+		}
+		if alias != h.alias {
+			h.reply(client, fmt.Sprintf("🚪 Closing room %s.", alias))
+		}
+		return
+	}
+
+	statuses := h.manager.roomStatuses()
+	var b strings.Builder
+	fmt.Fprintf(&b, "🏠 %d active rooms:", len(statuses))
+	for _, s := range statuses {
+		kind := "defined"
+		if !s.Defined {
+			kind = "ad-hoc"
+		}
+		last := "never"
+		if !s.LastActivity.IsZero() {
+			last = s.LastActivity.Local().Format("15:04:05")
+		}
+		fmt.Fprintf(&b, "\n  • %s (%s) - %d members, %d connections - open since %s - last activity %s",
+			s.Alias, kind, s.Members, s.Connections, s.CreatedAt.Local().Format("2006-01-02 15:04"), last)
+	}
+	h.reply(client, b.String())
+}
+
+// ServeRoomsAPI serves the admin room API, authenticated with a global
+// admin's session token:
+//
+//	GET    /api/rooms          list active rooms
+//	DELETE /api/rooms/<alias>  close a room, disconnecting its members
+func ServeRoomsAPI(manager *HubManager, w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	if token == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	session, err := manager.sessions.Verify(token)
+	if err != nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	// Tokens outlive account changes, so re-check the account itself
+	user, err := auth.CheckAccount(session.Username)
+	if err != nil || !user.IsAdmin {
+		log.Printf("Rejected room API request from %s (%s)", session.Username, r.RemoteAddr)
+		http.Error(w, "Admin privileges required", http.StatusForbidden)
+		return
+	}
+
+	alias := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/rooms"), "/")
+	switch {
+	case alias == "" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(manager.roomStatuses())
+	case alias != "" && r.Method == http.MethodDelete:
+		if !manager.closeRoom(alias, session.Username) {
+			http.Error(w, "No such active room", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,228 @@
+package hub
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+// parseDuration splits "[duration] [rest]" as given to /ban and /mute. A
+// leading word that is not a positive duration is part of rest.
+func parseDuration(args string) (time.Duration, string) {
+	first, rest, _ := strings.Cut(args, " ")
+	if d, err := time.ParseDuration(first); err == nil && d > 0 {
+		return d, strings.TrimSpace(rest)
+	}
+	return 0, args
+}
+
+// parseScope strips the -g flag that makes /ban and /unban global.
+func parseScope(args string) (bool, string) {
+	if rest, ok := strings.CutPrefix(args, "-g "); ok {
+		return true, strings.TrimSpace(rest)
+	}
+	return false, args
+}
+
+// mayModerate reports whether client may ban or mute target in this room,
+// replying with the reason, or with usage when no target was given, when
+// not. Only called from run.
+func (h *Hub) mayModerate(client *Client, command, target, usage string) bool {
+	if h.rank(client.username) < rankModerator {
+		h.reply(client, fmt.Sprintf("❌ Access denied. Only the room owner and moderators can use %s.", command))
+		return false
+	}
+	if target == "" {
+		h.reply(client, "❌ Usage: "+usage)
+		return false
+	}
+	if target == client.username {
+		h.reply(client, fmt.Sprintf("❌ You cannot use %s on yourself.", command))
+		return false
+	}
+	if h.rank(target) >= h.rank(client.username) {
+		h.reply(client, fmt.Sprintf("❌ You cannot %s %s %s.", strings.TrimPrefix(command, "/"), h.roleOf(target), target))
+		return false
+	}
+	return true
+}
+
+// ban handles /ban [-g] <username> [duration] [reason]. Room bans are for
+// the owner and moderators; -g bans from every room and is for admins.
+func (h *Hub) ban(client *Client, args string) {
+	global, args := parseScope(args)
+	target, rest, _ := strings.Cut(args, " ")
+	duration, reason := parseDuration(strings.TrimSpace(rest))
+
+	const usage = "/ban [-g] <username> [duration] [reason]"
+	if global {
+		if !client.isAdmin {
+			h.reply(client, "❌ Access denied. Admin privileges required for global bans.")
+			return
+		}
+		if target == "" {
+			h.reply(client, "❌ Usage: "+usage)
+			return
+		}
+		if target == client.username {
+			h.reply(client, "❌ You cannot use /ban on yourself.")
+			return
+		}
+	} else if !h.mayModerate(client, "/ban", target, usage) {
+		return
+	}
+
+	users, err := auth.LoadUsers()
+	if err != nil {
+		h.reply(client, fmt.Sprintf("❌ ban failed: %v", err))
+		return
+	}
+	account, exists := users.Users[target]
+	if !exists {
+		h.reply(client, fmt.Sprintf("❌ User '%s' not found.", target))
+		return
+	}
+	if account.IsAdmin {
+		h.reply(client, fmt.Sprintf("❌ %s is an admin and cannot be banned.", target))
+		return
+	}
+
+	ban := rooms.Ban{Username: target, Reason: reason, By: client.username, At: time.Now()}
+	if duration > 0 {
+		ban.Until = ban.At.Add(duration)
+	}
+	scope, where := h.pin, "room "+h.name
+	if global {
+		scope, where = "", "every room"
+	}
+	if err := rooms.AddBan(scope, ban); err != nil {
+		log.Printf("Saving ban in room %s failed: %v", h.name, err)
+		h.reply(client, fmt.Sprintf("❌ ban failed: %v", err))
+		return
+	}
+
+	role := h.roleOf(client.username)
+	if global {
+		role = "admin"
+	}
+	notice := fmt.Sprintf("🔨 You have been banned from %s by %s %s %s.", where, role, client.username, ban.Terms())
+	done := fmt.Sprintf("🔨 %s was banned from %s by %s %s %s", target, where, role, client.username, ban.Terms())
+	h.dropUser(target, notice)
+	h.deliver(systemMessage(done))
+	if global {
+		h.manager.disconnectUser(target, notice, done, h)
+	}
+}
+
+// unban handles /unban [-g] <username>.
+func (h *Hub) unban(client *Client, args string) {
+	global, target := parseScope(args)
+	if target == "" || strings.Contains(target, " ") {
+		h.reply(client, "❌ Usage: /unban [-g] <username>")
+		return
+	}
+
+	scope := h.pin
+	if global {
+		if !client.isAdmin {
+			h.reply(client, "❌ Access denied. Admin privileges required for global bans.")
+			return
+		}
+		scope = ""
+	} else if h.rank(client.username) < rankModerator {
+		h.reply(client, "❌ Access denied. Only the room owner and moderators can use /unban.")
+		return
+	}
+
+	if err := rooms.RemoveBan(scope, target); err != nil {
+		h.reply(client, fmt.Sprintf("❌ unban failed: %v", err))
+		return
+	}
+	h.reply(client, fmt.Sprintf("✅ %s is no longer banned.", target))
+}
+
+// listBans handles /bans: the room's bans, plus global bans for admins.
+func (h *Hub) listBans(client *Client) {
+	if h.rank(client.username) < rankModerator && !client.isAdmin {
+		h.reply(client, "❌ Access denied. Only the room owner and moderators can list bans.")
+		return
+	}
+
+	roomBans, err := rooms.ActiveBans(h.pin)
+	var globalBans []rooms.Ban
+	if err == nil && client.isAdmin {
+		globalBans, err = rooms.ActiveBans("")
+	}
+	if err != nil {
+		h.reply(client, fmt.Sprintf("❌ Failed to list bans: %v", err))
+		return
+	}
+	if len(roomBans) == 0 && len(globalBans) == 0 {
+		h.reply(client, "🔨 No bans in force.")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("🔨 Bans:")
+	for _, ban := range roomBans {
+		fmt.Fprintf(&b, "\n  • %s - by %s %s", ban.Username, ban.By, ban.Terms())
+	}
+	for _, ban := range globalBans {
+		fmt.Fprintf(&b, "\n  • %s - all rooms - by %s %s", ban.Username, ban.By, ban.Terms())
+	}
+	h.reply(client, b.String())
+}
+
+// isMuted reports whether username may not send messages in the room,
+// forgetting mutes that have run out. Only called from run.
+func (h *Hub) isMuted(username string) bool {
+	until, muted := h.muted[username]
+	if !muted {
+		return false
+	}
+	if !until.IsZero() && !time.Now().Before(until) {
+		delete(h.muted, username)
+		return false
+	}
+	return true
+}
+
+// mute handles /mute <username> [duration] and /unmute <username>. Mutes
+// last until lifted, the duration runs out or the room closes.
+func (h *Hub) mute(client *Client, command, args string) {
+	target, rest, _ := strings.Cut(args, " ")
+	usage := "/unmute <username>"
+	if command == "/mute" {
+		usage = "/mute <username> [duration]"
+	}
+	if !h.mayModerate(client, command, target, usage) {
+		return
+	}
+
+	role := h.roleOf(client.username)
+	if command == "/unmute" {
+		if !h.isMuted(target) {
+			h.reply(client, fmt.Sprintf("❌ %s is not muted.", target))
+			return
+		}
+		delete(h.muted, target)
+		h.sendToUser(target, systemMessage(fmt.Sprintf("🔊 You have been unmuted by %s %s.", role, client.username)))
+		h.deliver(systemMessage(fmt.Sprintf("🔊 %s was unmuted by %s %s", target, role, client.username)))
+		return
+	}
+
+	duration, _ := parseDuration(strings.TrimSpace(rest))
+	var until time.Time
+	terms := "until unmuted"
+	if duration > 0 {
+		until = time.Now().Add(duration)
+		terms = "for " + duration.String()
+	}
+	h.muted[target] = until
+	h.sendToUser(target, systemMessage(fmt.Sprintf("🔇 You have been muted by %s %s %s.", role, client.username, terms)))
+	h.deliver(systemMessage(fmt.Sprintf("🔇 %s was muted by %s %s %s", target, role, client.username, terms)))
+}
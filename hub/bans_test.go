@@ -0,0 +1,55 @@
+package hub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		args     string
+		want     time.Duration
+		wantRest string
+	}{
+		{"12h out of scope", 12 * time.Hour, "out of scope"},
+		{"90m", 90 * time.Minute, ""},
+		{"1h30m  spamming", 90 * time.Minute, "spamming"},
+		{"out of scope", 0, "out of scope"},
+		{"0s spamming", 0, "0s spamming"},
+		{"-1h spamming", 0, "-1h spamming"},
+		{"12 hours", 0, "12 hours"},
+		{"", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, rest := parseDuration(tt.args)
+			if got != tt.want || rest != tt.wantRest {
+				t.Fatalf("parseDuration(%q) = %v, %q; want %v, %q", tt.args, got, rest, tt.want, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		args       string
+		wantGlobal bool
+		wantRest   string
+	}{
+		{"-g bob 1h", true, "bob 1h"},
+		{"-g  bob", true, "bob"},
+		{"bob 1h", false, "bob 1h"},
+		{"bob -g", false, "bob -g"},
+		{"-gbob", false, "-gbob"},
+		{"-g", false, "-g"},
+		{"", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			global, rest := parseScope(tt.args)
+			if global != tt.wantGlobal || rest != tt.wantRest {
+				t.Fatalf("parseScope(%q) = %v, %q; want %v, %q", tt.args, global, rest, tt.wantGlobal, tt.wantRest)
+			}
+		})
+	}
+}
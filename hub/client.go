@@ -0,0 +1,109 @@
+package hub
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = 54 * time.Second // < pongWait
+	maxMessageSize = 1024 * 8
+)
+
+type Client struct {
+	conn        *websocket.Conn
+	send        chan []byte
+	hub         *Hub
+	username    string
+	displayName string
+	isAdmin     bool
+	joinedAt    time.Time
+
+	// version is the protocol version negotiated when the client connected.
+	version int
+
+	// since asks for history after this sequence number on join. resume is
+	// set when the client sent it at all, so since 0 asks for everything.
+	since  uint64
+	resume bool
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.done:
+		}
+		_ = c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("readPump unexpected close: %v", err)
+			}
+			break
+		}
+
+		var msg protocol.Message
+		if err := json.Unmarshal(message, &msg); err != nil {
+			log.Printf("Dropping malformed frame from %s: %v", c.username, err)
+			continue
+		}
+
+		// The hub owns c.send, so even replies to this client go through it
+		select {
+		case c.hub.incoming <- inbound{client: c, msg: msg}:
+		case <-c.hub.done:
+			return
+		}
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			w, err := c.conn.NextWriter(websocket.TextMessage)
+			if err != nil {
+				return
+			}
+			if _, err := w.Write(message); err != nil {
+				_ = w.Close()
+				return
+			}
+			_ = w.Close()
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
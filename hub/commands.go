@@ -0,0 +1,209 @@
+package hub
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+)
+
+// handleCommand runs a server-side chat command. It reports false when text
+// is not a known command, in which case it is relayed as a normal message.
+// Only called from run.
+func (h *Hub) handleCommand(client *Client, text string) bool {
+	name, args, _ := strings.Cut(text, " ")
+	args = strings.TrimSpace(args)
+
+	switch name {
+	case "/who":
+		h.who(client)
+	case "/kick":
+		h.kick(client, args)
+	case "/ban":
+		h.ban(client, args)
+	case "/unban":
+		h.unban(client, args)
+	case "/bans":
+		h.listBans(client)
+	case "/mute", "/unmute":
+		h.mute(client, name, args)
+	case "/mod", "/unmod", "/owner":
+		h.manageRole(client, name, args)
+	case "/topic":
+		h.setTopic(client, args)
+	case "/motd":
+		h.setMOTD(client, args)
+	case "/ttl":
+		h.setDefaultTTL(client, args)
+	case "/disable", "/enable", "/deluser", "/lock":
+		h.manageAccount(client, name, args)
+	case "/rooms":
+		h.listRooms(client, args)
+	case "/lockouts":
+		h.listLockouts(client)
+	case "/unlock":
+		h.unlock(client, args)
+	default:
+		return false
+	}
+	return true
+}
+
+// dropUser disconnects every connection of username from this hub after
+// sending it notice. Only called from run.
+func (h *Hub) dropUser(username, notice string) bool {
+	var targets []*Client
+	for target := range h.clients {
+		if target.username == username {
+			targets = append(targets, target)
+		}
+	}
+	for _, target := range targets {
+		h.reply(target, notice)
+		h.removeClient(target)
+	}
+	return len(targets) > 0
+}
+
+func (h *Hub) kick(client *Client, targetUser string) {
+	if h.rank(client.username) < rankModerator {
+		h.reply(client, "❌ Access denied. Only the room owner and moderators can kick.")
+		return
+	}
+
+	if targetUser == "" {
+		h.reply(client, "❌ Usage: /kick <username>")
+		return
+	}
+
+	if h.rank(targetUser) >= h.rank(client.username) {
+		h.reply(client, fmt.Sprintf("❌ You cannot kick %s %s.", h.roleOf(targetUser), targetUser))
+		return
+	}
+
+	role := h.roleOf(client.username)
+	if h.dropUser(targetUser, fmt.Sprintf("🚫 You have been kicked by %s %s.", role, client.username)) {
+		h.deliver(systemMessage(fmt.Sprintf("🚫 %s was kicked by %s %s", targetUser, role, client.username)))
+	} else {
+		h.reply(client, fmt.Sprintf("❌ User '%s' not found in room.", targetUser))
+	}
+}
+
+// manageAccount handles /disable, /enable, /deluser and /lock for global
+// admins. Accounts that lose access are disconnected from every room.
+func (h *Hub) manageAccount(client *Client, command, args string) {
+	if !client.isAdmin {
+		h.reply(client, "❌ Access denied. Admin privileges required.")
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 || (command == "/lock" && len(fields) != 2) || (command != "/lock" && len(fields) != 1) {
+		if command == "/lock" {
+			h.reply(client, "❌ Usage: /lock <username> <duration, e.g. 30m or 12h>")
+		} else {
+			h.reply(client, fmt.Sprintf("❌ Usage: %s <username>", command))
+		}
+		return
+	}
+	target := fields[0]
+
+	var err error
+	var done, notice string
+	switch command {
+	case "/disable":
+		err = auth.DisableUser(client.username, client.isAdmin, target)
+		done = fmt.Sprintf("⛔ Account '%s' disabled by admin %s", target, client.username)
+		notice = "⛔ Your account has been disabled by an admin."
+	case "/enable":
+		err = auth.EnableUser(client.username, client.isAdmin, target)
+		done = fmt.Sprintf("✅ Account '%s' re-enabled.", target)
+	case "/deluser":
+		err = auth.DeleteUser(client.username, client.isAdmin, target)
+		done = fmt.Sprintf("🗑️ Account '%s' deleted by admin %s", target, client.username)
+		notice = "🗑️ Your account has been deleted by an admin."
+	case "/lock":
+		var duration time.Duration
+		duration, err = time.ParseDuration(fields[1])
+		if err == nil {
+			err = auth.LockUser(client.username, client.isAdmin, target, duration)
+		}
+		done = fmt.Sprintf("🔒 Account '%s' locked for %s by admin %s", target, fields[1], client.username)
+		notice = "🔒 Your account has been locked by an admin."
+	}
+	if err != nil {
+		h.reply(client, fmt.Sprintf("❌ %s failed: %v", strings.TrimPrefix(command, "/"), err))
+		return
+	}
+
+	if notice == "" {
+		h.reply(client, done)
+		return
+	}
+	if h.dropUser(target, notice) {
+		h.deliver(systemMessage(done))
+	} else {
+		h.reply(client, done)
+	}
+	h.manager.disconnectUser(target, notice, done, h)
+}
+
+// listLockouts shows accounts and source addresses with failed logins.
+func (h *Hub) listLockouts(client *Client) {
+	if !client.isAdmin {
+		h.reply(client, "❌ Access denied. Admin privileges required.")
+		return
+	}
+
+	accounts, err := auth.ListLockouts()
+	if err != nil {
+		h.reply(client, fmt.Sprintf("❌ Failed to list lockouts: %v", err))
+		return
+	}
+	addrs := h.manager.limiter.list()
+	if len(accounts) == 0 && len(addrs) == 0 {
+		h.reply(client, "🔒 No failed logins on record.")
+		return
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("🔒 Failed logins:")
+	for _, l := range accounts {
+		fmt.Fprintf(&b, "\n  • user %s - %d failed%s", l.Username, l.FailedLogins, lockedSuffix(now, l.LockedUntil))
+	}
+	for _, l := range addrs {
+		fmt.Fprintf(&b, "\n  • addr %s - %d failed%s", l.Addr, l.Failures, lockedSuffix(now, l.LockedUntil))
+	}
+	h.reply(client, b.String())
+}
+
+func lockedSuffix(now, lockedUntil time.Time) string {
+	if now.Before(lockedUntil) {
+		return " [LOCKED until " + lockedUntil.Local().Format("15:04:05") + "]"
+	}
+	return ""
+}
+
+// unlock clears the failure record of an account or a source address.
+func (h *Hub) unlock(client *Client, target string) {
+	if !client.isAdmin {
+		h.reply(client, "❌ Access denied. Admin privileges required.")
+		return
+	}
+	if target == "" {
+		h.reply(client, "❌ Usage: /unlock <username|address>")
+		return
+	}
+
+	if h.manager.limiter.clear(target) {
+		h.reply(client, fmt.Sprintf("🔓 Address %s unlocked.", target))
+		return
+	}
+	if err := auth.UnlockUser(client.username, client.isAdmin, target); err != nil {
+		h.reply(client, fmt.Sprintf("❌ unlock failed: %v", err))
+		return
+	}
+	h.reply(client, fmt.Sprintf("🔓 Account '%s' unlocked.", target))
+}
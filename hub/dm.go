@@ -0,0 +1,101 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+)
+
+// trackPresence records that username gained (delta 1) or lost (delta -1)
+// a connection to h, so direct messages can find users in any room.
+func (m *HubManager) trackPresence(username string, h *Hub, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hubs := m.online[username]
+	if hubs == nil {
+		hubs = make(map[*Hub]int)
+		m.online[username] = hubs
+	}
+	hubs[h] += delta
+	if hubs[h] <= 0 {
+		delete(hubs, h)
+	}
+	if len(hubs) == 0 {
+		delete(m.online, username)
+	}
+}
+
+// hubsOf returns the hubs username is connected to.
+func (m *HubManager) hubsOf(username string) []*Hub {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hubs := make([]*Hub, 0, len(m.online[username]))
+	for h := range m.online[username] {
+		hubs = append(hubs, h)
+	}
+	return hubs
+}
+
+// sendToUser queues message for every connection username has in h. Only
+// called from run.
+func (h *Hub) sendToUser(username string, message []byte) {
+	for client := range h.clients {
+		if client.username == username {
+			h.sendTo(client, message)
+		}
+	}
+}
+
+// directMessage relays a DM to every connection of its recipient, in this
+// room or any other, and echoes it back to the sender. DMs are never
+// written to history. Only called from run.
+func (h *Hub) directMessage(client *Client, msg protocol.Message) {
+	target := strings.TrimSpace(msg.To)
+	if target == "" || strings.TrimSpace(msg.Message) == "" {
+		h.reply(client, "❌ Usage: /msg <username> <text>")
+		return
+	}
+	if target == client.username {
+		h.reply(client, "❌ You cannot send a direct message to yourself.")
+		return
+	}
+
+	hubs := h.manager.hubsOf(target)
+	if len(hubs) == 0 {
+		h.reply(client, fmt.Sprintf("❌ User '%s' is not online.", target))
+		return
+	}
+
+	data, err := json.Marshal(protocol.Message{
+		Type:      protocol.TypeDM,
+		Message:   msg.Message,
+		Username:  client.username,
+		To:        target,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Printf("Encode failed in room %s: %v", h.pin, err)
+		return
+	}
+
+	for _, other := range hubs {
+		if other == h {
+			h.sendToUser(target, data)
+			continue
+		}
+		// Other hubs own their clients' send channels
+		go func(other *Hub) {
+			select {
+			case other.exec <- func() { other.sendToUser(target, data) }:
+			case <-other.done:
+			}
+		}(other)
+	}
+	h.sendTo(client, data)
+}
@@ -0,0 +1,138 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+)
+
+// maxRecent bounds the relayed messages a hub keeps in memory for edits and
+// deletes; older ones are looked up in history when it is enabled.
+const maxRecent = 500
+
+// remember keeps msg for later edits and deletes. Only called from run.
+func (h *Hub) remember(msg protocol.Message) {
+	h.recent[msg.Seq] = msg
+	h.recentOrder = append(h.recentOrder, msg.Seq)
+	if len(h.recentOrder) > maxRecent {
+		delete(h.recent, h.recentOrder[0])
+		h.recentOrder = h.recentOrder[1:]
+	}
+}
+
+// lookup finds the chat message numbered seq in this room. History is
+// indexed by sequence number, so an old message costs one line to decrypt
+// and an unknown one nothing. Only called from run.
+func (h *Hub) lookup(seq uint64) (protocol.Message, bool) {
+	if msg, ok := h.recent[seq]; ok {
+		return msg, true
+	}
+	if h.history == nil || seq == 0 {
+		return protocol.Message{}, false
+	}
+	msg, ok, err := h.history.Find(seq)
+	if err != nil {
+		log.Printf("Reading history for room %s failed: %v", h.name, err)
+	}
+	return msg, ok
+}
+
+// mayChange reports whether client may edit or delete target, replying
+// with the reason when not. Authors and the room's moderators may. Only
+// called from run.
+func (h *Hub) mayChange(client *Client, seq uint64, verb string) (protocol.Message, bool) {
+	target, ok := h.lookup(seq)
+	if !ok {
+		h.reply(client, fmt.Sprintf("❌ No message #%d in this room.", seq))
+		return target, false
+	}
+	if target.Username != client.username && h.rank(client.username) < rankModerator {
+		h.reply(client, fmt.Sprintf("❌ You can only %s your own messages.", verb))
+		return target, false
+	}
+	return target, true
+}
+
+// editMessage replaces the body of a message, in history too, and tells
+// every client to redraw it. Only called from run.
+func (h *Hub) editMessage(client *Client, msg protocol.Message) {
+	if msg.Seq == 0 || strings.TrimSpace(msg.Message) == "" {
+		h.reply(client, "❌ Usage: /edit <id> <text>")
+		return
+	}
+	if h.isMuted(client.username) {
+		h.reply(client, "🔇 You are muted in this room; your edit was not applied.")
+		return
+	}
+	target, ok := h.mayChange(client, msg.Seq, "edit")
+	if !ok {
+		return
+	}
+
+	target.Message = msg.Message
+	target.Enc = msg.Enc
+	target.Edited = true
+	target.By = client.username
+	if err := h.store(target); err != nil {
+		log.Printf("Editing history for room %s failed: %v", h.name, err)
+	}
+
+	edit := target
+	edit.Type = protocol.TypeEdit
+	data, err := json.Marshal(edit)
+	if err != nil {
+		log.Printf("Encode failed in room %s: %v", h.pin, err)
+		return
+	}
+	h.touch(true)
+	h.deliver(data)
+}
+
+// deleteMessage retracts a message, removing it from history, and tells
+// every client to wipe it. Only called from run.
+func (h *Hub) deleteMessage(client *Client, msg protocol.Message) {
+	if msg.Seq == 0 {
+		h.reply(client, "❌ Usage: /delete <id>")
+		return
+	}
+	target, ok := h.mayChange(client, msg.Seq, "delete")
+	if !ok {
+		return
+	}
+
+	delete(h.recent, target.Seq)
+	if h.history != nil {
+		if _, err := h.history.Replace(target.Seq, nil); err != nil {
+			log.Printf("Deleting from history for room %s failed: %v", h.name, err)
+		}
+	}
+
+	data, err := json.Marshal(protocol.Message{
+		Type:     protocol.TypeDelete,
+		Seq:      target.Seq,
+		Username: target.Username,
+		By:       client.username,
+	})
+	if err != nil {
+		log.Printf("Encode failed in room %s: %v", h.pin, err)
+		return
+	}
+	h.touch(true)
+	h.deliver(data)
+}
+
+// store records a changed message wherever the room keeps it. Only called
+// from run.
+func (h *Hub) store(msg protocol.Message) error {
+	if _, ok := h.recent[msg.Seq]; ok {
+		h.recent[msg.Seq] = msg
+	}
+	if h.history == nil {
+		return nil
+	}
+	_, err := h.history.Replace(msg.Seq, &msg)
+	return err
+}
@@ -0,0 +1,197 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+// Files are held in memory until they expire or the room closes; the server
+// never writes them to disk, and in encrypted rooms only sees ciphertext.
+const (
+	maxTransfers    = 8
+	maxFileIDLength = 64
+	uploadIdle      = 2 * time.Minute
+	transferTTL     = time.Hour
+)
+
+// transfer is one offered file. chunks fill in as the sender uploads them;
+// the offer is announced once all have arrived.
+type transfer struct {
+	offer    protocol.Message
+	chunks   []string
+	received int
+	expires  time.Time
+}
+
+func (t *transfer) complete() bool {
+	return t.received == len(t.chunks)
+}
+
+// fileCap is the largest file the room accepts, or zero when transfers are
+// disabled. A room's own cap replaces the server default.
+func fileCap(room rooms.Room, serverCap int64) int64 {
+	switch {
+	case room.MaxFileSize < 0:
+		return 0
+	case room.MaxFileSize > 0:
+		return room.MaxFileSize
+	}
+	return serverCap
+}
+
+// validFileID accepts the IDs clients generate: short and printable, so
+// they can be typed back with /accept.
+func validFileID(id string) bool {
+	if id == "" || len(id) > maxFileIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneTransfers forgets expired files and stalled uploads. Only called
+// from run.
+func (h *Hub) pruneTransfers() {
+	now := time.Now()
+	for id, t := range h.transfers {
+		if now.After(t.expires) {
+			delete(h.transfers, id)
+		}
+	}
+}
+
+// offerFile starts an upload after checking it against the room's cap. Only
+// called from run.
+func (h *Hub) offerFile(client *Client, msg protocol.Message) {
+	if h.maxFileSize == 0 {
+		h.reply(client, "❌ File transfer is disabled in this room.")
+		return
+	}
+	if h.isMuted(client.username) {
+		h.reply(client, "🔇 You are muted in this room; your file was not sent.")
+		return
+	}
+	file := msg.File
+	if file == nil || !validFileID(file.ID) || msg.Message == "" || file.Size <= 0 ||
+		file.Chunks != protocol.FileChunks(file.Size) || file.SHA256 == "" {
+		h.reply(client, "❌ Rejected file: malformed offer.")
+		return
+	}
+	if file.Size > h.maxFileSize {
+		h.reply(client, fmt.Sprintf("❌ Rejected file: %s exceeds this room's limit of %s.",
+			protocol.FormatSize(file.Size), protocol.FormatSize(h.maxFileSize)))
+		return
+	}
+
+	h.pruneTransfers()
+	if _, exists := h.transfers[file.ID]; exists {
+		h.reply(client, fmt.Sprintf("❌ Rejected file: ID %s is already in use in this room.", file.ID))
+		return
+	}
+	if len(h.transfers) >= maxTransfers {
+		h.reply(client, "❌ Rejected file: too many files are on offer in this room; try again later.")
+		return
+	}
+
+	h.transfers[file.ID] = &transfer{
+		offer: protocol.Message{
+			Type:     protocol.TypeFileOffer,
+			Message:  msg.Message,
+			Username: client.username,
+			Enc:      msg.Enc,
+			File: &protocol.FileInfo{
+				ID:     file.ID,
+				Size:   file.Size,
+				Chunks: file.Chunks,
+				SHA256: file.SHA256,
+			},
+		},
+		chunks:  make([]string, file.Chunks),
+		expires: time.Now().Add(uploadIdle),
+	}
+}
+
+// uploadChunk stores one chunk of the sender's upload and announces the
+// offer once the file is complete. A bad chunk cancels the upload. Only
+// called from run.
+func (h *Hub) uploadChunk(client *Client, msg protocol.Message) {
+	if msg.File == nil {
+		h.reply(client, "❌ Rejected file chunk: no file ID.")
+		return
+	}
+	t, ok := h.transfers[msg.File.ID]
+	if !ok || t.offer.Username != client.username || t.complete() {
+		h.reply(client, fmt.Sprintf("❌ Rejected file chunk: no upload %s in progress.", msg.File.ID))
+		return
+	}
+	index := msg.File.Index
+	if index < 0 || index >= len(t.chunks) || t.chunks[index] != "" || msg.Message == "" || msg.Enc != t.offer.Enc {
+		delete(h.transfers, msg.File.ID)
+		h.reply(client, fmt.Sprintf("❌ Upload %s cancelled: bad chunk %d.", msg.File.ID, index))
+		return
+	}
+
+	t.chunks[index] = msg.Message
+	t.received++
+	t.expires = time.Now().Add(uploadIdle)
+	if !t.complete() {
+		return
+	}
+
+	t.expires = time.Now().Add(transferTTL)
+	t.offer.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	data, err := json.Marshal(t.offer)
+	if err != nil {
+		log.Printf("Encode failed in room %s: %v", h.pin, err)
+		return
+	}
+	h.touch(true)
+	h.deliver(data)
+}
+
+// acceptFile sends a window of chunks of a complete file, starting at the
+// requested index. Chunks that do not fit the client's send buffer are
+// dropped like any frame; the client asks for them again. Only called from
+// run.
+func (h *Hub) acceptFile(client *Client, msg protocol.Message) {
+	if msg.File == nil {
+		h.reply(client, "❌ Usage: /accept <id>")
+		return
+	}
+	h.pruneTransfers()
+	t, ok := h.transfers[msg.File.ID]
+	if !ok || !t.complete() {
+		h.reply(client, fmt.Sprintf("❌ No file %s on offer in this room; offers expire after %s.", msg.File.ID, transferTTL))
+		return
+	}
+	start := msg.File.Index
+	if start < 0 || start >= len(t.chunks) {
+		h.reply(client, fmt.Sprintf("❌ File %s has no chunk %d.", msg.File.ID, start))
+		return
+	}
+
+	end := min(start+protocol.FileWindow, len(t.chunks))
+	for index := start; index < end; index++ {
+		data, err := json.Marshal(protocol.Message{
+			Type:     protocol.TypeFileChunk,
+			Message:  t.chunks[index],
+			Username: t.offer.Username,
+			Enc:      t.offer.Enc,
+			File:     &protocol.FileInfo{ID: t.offer.File.ID, Index: index},
+		})
+		if err != nil {
+			log.Printf("Encode failed in room %s: %v", h.pin, err)
+			return
+		}
+		h.sendTo(client, data)
+	}
+}
@@ -0,0 +1,249 @@
+package hub
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	handshakeTimeout      = 30 * time.Second
+	passwordChangeTimeout = 2 * time.Minute
+	totpTimeout           = 2 * time.Minute
+)
+
+// serverHandshake runs the challenge-response login on conn and returns the
+// verified session. The connection should be closed if it fails. Failures
+// count against both the account and the source address addr. version is
+// the negotiated protocol version, echoed in the challenge.
+func serverHandshake(conn *websocket.Conn, manager *HubManager, addr string, version int) (auth.Session, error) {
+	sessions := manager.sessions
+
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	conn.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetWriteDeadline(time.Time{})
+
+	var hello protocol.AuthMessage
+	if err := conn.ReadJSON(&hello); err != nil {
+		return auth.Session{}, err
+	}
+	if hello.Type != protocol.TypeAuthHello || hello.Username == "" {
+		return auth.Session{}, fmt.Errorf("expected %s", protocol.TypeAuthHello)
+	}
+	username := hello.Username
+
+	db, err := auth.LoadUsers()
+	if err != nil {
+		return auth.Session{}, err
+	}
+	user, exists := db.Users[username]
+
+	// Unknown users get a stable decoy salt so the challenge does not reveal
+	// whether an account exists.
+	salt := user.Salt
+	if !exists {
+		salt = base64.StdEncoding.EncodeToString(sessions.DecoySalt(username))
+	}
+
+	nonce, err := auth.NewNonce()
+	if err != nil {
+		return auth.Session{}, err
+	}
+
+	challenge := protocol.AuthMessage{
+		Type:    protocol.TypeAuthChallenge,
+		Version: version,
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+		Salt:    salt,
+	}
+	if err := conn.WriteJSON(challenge); err != nil {
+		return auth.Session{}, err
+	}
+
+	var response protocol.AuthMessage
+	if err := conn.ReadJSON(&response); err != nil {
+		return auth.Session{}, err
+	}
+	if response.Type != protocol.TypeAuthResponse {
+		return auth.Session{}, fmt.Errorf("expected %s", protocol.TypeAuthResponse)
+	}
+
+	fail := func() (auth.Session, error) {
+		manager.limiter.failure(addr, username)
+		if exists {
+			if _, err := auth.RecordLoginFailure(username); err != nil {
+				log.Printf("Recording failed login for %q: %v", username, err)
+			}
+		}
+		_ = conn.WriteJSON(protocol.AuthMessage{Type: protocol.TypeAuthFailed, Message: "Invalid username or password"})
+		return auth.Session{}, fmt.Errorf("authentication failed for user %q", username)
+	}
+
+	if !exists {
+		return fail()
+	}
+
+	// Locked accounts are refused before the proof is even checked, with the
+	// same reply as a wrong password so the lock does not reveal the account.
+	// The attempt still counts against the address.
+	if err := user.CheckNotLocked(time.Now()); err != nil {
+		manager.limiter.failure(addr, username)
+		_ = conn.WriteJSON(protocol.AuthMessage{Type: protocol.TypeAuthFailed, Message: "Invalid username or password"})
+		return auth.Session{}, fmt.Errorf("user %q: %w", username, err)
+	}
+
+	proof, err := base64.StdEncoding.DecodeString(response.Proof)
+	if err != nil {
+		return fail()
+	}
+	verifier, err := base64.StdEncoding.DecodeString(user.Verifier)
+	if err != nil {
+		return auth.Session{}, err
+	}
+	if !auth.VerifyProof(verifier, nonce, username, proof) {
+		return fail()
+	}
+
+	// Account state is only revealed to someone who knows the password
+	if err := user.CheckActive(time.Now()); err != nil {
+		_ = conn.WriteJSON(protocol.AuthMessage{Type: protocol.TypeAuthFailed, Message: "Login refused: " + err.Error()})
+		return auth.Session{}, fmt.Errorf("user %q: %w", username, err)
+	}
+
+	if db.NeedsTOTP(user) {
+		if err := serverSecondFactor(conn, username, user); err != nil {
+			if errors.Is(err, auth.ErrInvalidCode) {
+				manager.limiter.failure(addr, username)
+			}
+			_ = conn.WriteJSON(protocol.AuthMessage{Type: protocol.TypeAuthFailed, Message: "Two-factor authentication failed: " + err.Error()})
+			return auth.Session{}, fmt.Errorf("user %q: %w", username, err)
+		}
+	} else if err := auth.RecordLoginSuccess(username); err != nil {
+		log.Printf("Recording login for %q: %v", username, err)
+	}
+	manager.limiter.success(addr, username)
+
+	if user.MustChangePassword || response.ChangePassword {
+		if err := serverPasswordChange(conn, username, user, nonce); err != nil {
+			_ = conn.WriteJSON(protocol.AuthMessage{Type: protocol.TypeAuthFailed, Message: "Password change failed: " + err.Error()})
+			return auth.Session{}, err
+		}
+	}
+
+	token, session, err := sessions.Issue(username, user.IsAdmin)
+	if err != nil {
+		return auth.Session{}, err
+	}
+
+	ok := protocol.AuthMessage{
+		Type:      protocol.TypeAuthOK,
+		Username:  session.Username,
+		IsAdmin:   session.IsAdmin,
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := conn.WriteJSON(ok); err != nil {
+		return auth.Session{}, err
+	}
+
+	return session, nil
+}
+
+// serverSecondFactor asks for a TOTP code, or walks the client through
+// enrollment when 2FA is required but not set up yet. It only runs after the
+// client has proven the password.
+func serverSecondFactor(conn *websocket.Conn, username string, user auth.User) error {
+	var secret string
+	request := protocol.AuthMessage{Type: protocol.TypeAuthTOTPRequired, Message: "Enter the code from your authenticator app."}
+	if user.TOTPSecret == "" {
+		var err error
+		secret, err = auth.GenerateTOTPSecret()
+		if err != nil {
+			return err
+		}
+		request = protocol.AuthMessage{
+			Type:    protocol.TypeAuthTOTPEnroll,
+			Message: "Two-factor authentication is required for admin accounts. Add this account to your authenticator app, then enter its code.",
+			URI:     auth.TOTPURI(username, secret),
+		}
+	}
+	if err := conn.WriteJSON(request); err != nil {
+		return err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(totpTimeout))
+
+	var reply protocol.AuthMessage
+	if err := conn.ReadJSON(&reply); err != nil {
+		return err
+	}
+	if reply.Type != protocol.TypeAuthTOTP {
+		return fmt.Errorf("expected %s", protocol.TypeAuthTOTP)
+	}
+	conn.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+
+	if secret != "" {
+		return auth.EnrollTOTP(username, secret, reply.Code)
+	}
+	return auth.VerifyUserTOTP(username, reply.Code)
+}
+
+// serverPasswordChange asks the client for a new salt and verifier and
+// stores them. It only runs after the client has proven the current password.
+//
+// The server never sees the new password, so it cannot enforce a minimum
+// length or that it differs from the old one; clients check that. It does
+// require a fresh salt and a proof signed with the new login key over the
+// login nonce, so the verifier is one the client can actually log in with.
+func serverPasswordChange(conn *websocket.Conn, username string, current auth.User, nonce []byte) error {
+	prompt := "Choose a new password."
+	if current.MustChangePassword {
+		prompt = "Your password was reset by an admin. Choose a new password."
+	}
+	if err := conn.WriteJSON(protocol.AuthMessage{Type: protocol.TypeAuthChangeRequired, Message: prompt}); err != nil {
+		return err
+	}
+
+	// The user is typing a new password on the other end
+	conn.SetReadDeadline(time.Now().Add(passwordChangeTimeout))
+
+	var change protocol.AuthMessage
+	if err := conn.ReadJSON(&change); err != nil {
+		return err
+	}
+	if change.Type != protocol.TypeAuthChange {
+		return fmt.Errorf("expected %s", protocol.TypeAuthChange)
+	}
+	conn.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+
+	if change.Salt == current.Salt || change.Verifier == current.Verifier {
+		return fmt.Errorf("new password must use a fresh salt")
+	}
+	verifier, err := base64.StdEncoding.DecodeString(change.Verifier)
+	if err != nil {
+		return fmt.Errorf("invalid password verifier")
+	}
+	proof, err := base64.StdEncoding.DecodeString(change.Proof)
+	if err != nil || !auth.VerifyProof(verifier, nonce, username, proof) {
+		return fmt.Errorf("new password was not proven")
+	}
+
+	return auth.UpdateUsers(func(db *auth.UserDatabase) error {
+		user, exists := db.Users[username]
+		if !exists || user.Verifier != current.Verifier {
+			return fmt.Errorf("account changed during login; try again")
+		}
+		if err := user.SetVerifier(change.Salt, change.Verifier); err != nil {
+			return err
+		}
+		db.Users[username] = user
+		return nil
+	})
+}
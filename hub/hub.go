@@ -0,0 +1,484 @@
+// Package hub relays chat traffic between authenticated clients that share
+// a room PIN.
+package hub
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/history"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+// inbound is a frame read from a client, handed to the hub goroutine.
+type inbound struct {
+	client *Client
+	msg    protocol.Message
+}
+
+type Hub struct {
+	clients    map[*Client]bool
+	incoming   chan inbound
+	register   chan *Client
+	unregister chan *Client
+	exec       chan func()
+	done       chan struct{}
+	manager    *HubManager
+	pin        string
+	name       string
+	alias      string
+	salt       []byte
+
+	// defined is set for rooms with a persistent definition, whose roles
+	// are saved back to it.
+	defined    bool
+	owner      string
+	moderators map[string]bool
+
+	// roomOwner is the owner saved with the room. When they leave a defined
+	// room, owner names a stand-in until they return; see transferOwnership.
+	roomOwner string
+
+	// muted maps muted users to when the mute ends; zero lasts until
+	// /unmute or the hub closes.
+	muted map[string]time.Time
+
+	// defaultTTL, in seconds, self-destructs every message in the room.
+	defaultTTL int
+
+	// topic and motd greet every client that joins.
+	topic string
+	motd  string
+
+	// history is the room's message log when history is enabled.
+	history *history.Log
+
+	// seq numbers every relayed chat message in the room.
+	seq uint64
+
+	// recent holds the last relayed messages, oldest first in recentOrder,
+	// so they can be edited or deleted. Self-destructing messages are not
+	// kept.
+	recent      map[uint64]protocol.Message
+	recentOrder []uint64
+
+	// maxFileSize caps file transfers in bytes, zero disabling them, and
+	// transfers holds the files on offer by ID.
+	maxFileSize int64
+	transfers   map[string]*transfer
+
+	// closing is set while an admin closes the room, so members leaving
+	// are not announced and ownership stays put.
+	closing bool
+
+	// status is written by run and read by the admin API under statusMu.
+	statusMu sync.Mutex
+	status   roomStatus
+}
+
+// newHub creates the hub for room. Ad-hoc rooms have no definition; their
+// ID and name are the PIN the first client asked for.
+func newHub(room rooms.Room, defined bool, manager *HubManager) (*Hub, error) {
+	var roomLog *history.Log
+	var salt []byte
+	var err error
+	if manager.opts.History != nil {
+		// Rooms with history keep their salt so old messages stay readable
+		roomLog, err = manager.opts.History.Open(room.ID)
+		if err != nil {
+			return nil, err
+		}
+		salt = roomLog.Salt()
+	} else {
+		salt, err = e2e.NewSalt()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	moderators := make(map[string]bool)
+	for _, username := range room.Moderators {
+		moderators[username] = true
+	}
+
+	hub := &Hub{
+		clients:     make(map[*Client]bool),
+		incoming:    make(chan inbound),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		exec:        make(chan func()),
+		done:        make(chan struct{}),
+		manager:     manager,
+		pin:         room.ID,
+		name:        room.Name,
+		alias:       roomAlias(room.ID, room.Name, defined),
+		salt:        salt,
+		defined:     defined,
+		owner:       room.Owner,
+		roomOwner:   room.Owner,
+		moderators:  moderators,
+		muted:       make(map[string]time.Time),
+		recent:      make(map[uint64]protocol.Message),
+		maxFileSize: fileCap(room, manager.opts.MaxFileSize),
+		transfers:   make(map[string]*transfer),
+		defaultTTL:  room.DefaultTTL,
+		topic:       room.Topic,
+		motd:        room.MOTD,
+		history:     roomLog,
+	}
+	hub.status = roomStatus{Alias: hub.alias, Defined: defined, CreatedAt: time.Now().UTC()}
+	if roomLog != nil {
+		hub.seq = roomLog.LastSeq()
+	}
+	return hub, nil
+}
+
+// deliver fans a message out to every client, dropping clients whose send
+// buffer is full. Only called from run.
+func (h *Hub) deliver(message []byte) {
+	var slow []*Client
+	for client := range h.clients {
+		select {
+		case client.send <- message:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	for _, client := range slow {
+		h.removeClient(client)
+	}
+}
+
+// removeClient disconnects client. When it was the user's last connection
+// the room is told they left, and ownership moves on if they owned it.
+// Only called from run.
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	delete(h.clients, client)
+	close(client.send)
+	h.manager.trackPresence(client.username, h, -1)
+	h.touch(false)
+	if h.closing || h.present(client.username) {
+		return
+	}
+	h.announcePresence(client, protocol.PresenceLeave)
+	if client.username == h.owner {
+		h.transferOwnership()
+	}
+}
+
+func (h *Hub) run(ctx context.Context) {
+	defer close(h.done)
+	if h.history != nil {
+		defer h.history.Close()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case client := <-h.register:
+			returning := h.present(client.username)
+			h.clients[client] = true
+			h.manager.trackPresence(client.username, h, 1)
+			h.touch(true)
+			client.send <- h.roomInfo(client)
+			if !returning {
+				h.announcePresence(client, protocol.PresenceJoin)
+			}
+			client.send <- systemMessage("👋 Welcome to room " + h.name)
+			h.greetTopic(client)
+			h.replay(client)
+			if h.owner == "" {
+				h.setOwner(client.username)
+			} else if client.username == h.roomOwner && h.owner != h.roomOwner {
+				h.restoreOwner()
+			}
+			h.greetRole(client)
+		case client := <-h.unregister:
+			h.removeClient(client)
+		case in := <-h.incoming:
+			if _, ok := h.clients[in.client]; ok {
+				h.handle(in.client, in.msg)
+			}
+		case fn := <-h.exec:
+			fn()
+		}
+
+		// The room closes once its last client is gone, however it left
+		if len(h.clients) == 0 {
+			return
+		}
+	}
+}
+
+// roomInfo encodes the room_info frame that opens a client's session: the
+// room's key salt, the protocol version negotiated with the client, the
+// file size cap and the newest message number.
+func (h *Hub) roomInfo(client *Client) []byte {
+	data, _ := json.Marshal(protocol.Message{
+		Type:        protocol.TypeRoomInfo,
+		Version:     client.version,
+		Salt:        base64.StdEncoding.EncodeToString(h.salt),
+		MaxFileSize: h.maxFileSize,
+		Seq:         h.seq,
+	})
+	return data
+}
+
+// systemMessage encodes a system notice.
+func systemMessage(text string) []byte {
+	data, _ := json.Marshal(protocol.Message{Type: protocol.TypeSystem, Message: text})
+	return data
+}
+
+// sendTo queues a frame for one client without blocking the hub. Only
+// called from run.
+func (h *Hub) sendTo(client *Client, message []byte) {
+	select {
+	case client.send <- message:
+	default:
+	}
+}
+
+// reply queues a system notice for a single client. Only called from run.
+func (h *Hub) reply(client *Client, text string) {
+	h.sendTo(client, systemMessage(text))
+}
+
+// handle processes one frame from a registered client. The server is the
+// authority on who sent a message and when, so sender, timestamp and
+// sequence number are always overwritten before relaying.
+func (h *Hub) handle(client *Client, msg protocol.Message) {
+	switch msg.Type {
+	case protocol.TypePing:
+		pong, _ := json.Marshal(protocol.Message{
+			Type:      protocol.TypePong,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		h.sendTo(client, pong)
+		return
+	case protocol.TypeDM:
+		h.directMessage(client, msg)
+		return
+	case protocol.TypeEdit:
+		h.editMessage(client, msg)
+		return
+	case protocol.TypeDelete:
+		h.deleteMessage(client, msg)
+		return
+	case protocol.TypeFileOffer:
+		h.offerFile(client, msg)
+		return
+	case protocol.TypeFileChunk:
+		h.uploadChunk(client, msg)
+		return
+	case protocol.TypeFileAccept:
+		h.acceptFile(client, msg)
+		return
+	case protocol.TypeMessage:
+	default:
+		log.Printf("Rejected %q frame from %s in room %s", msg.Type, client.username, h.pin)
+		h.reply(client, fmt.Sprintf("❌ Rejected message: clients cannot send %q frames.", msg.Type))
+		return
+	}
+
+	if msg.Username != "" && msg.Username != client.username {
+		log.Printf("Rejected spoofed sender %q from %s in room %s", msg.Username, client.username, h.pin)
+		h.reply(client, "❌ Rejected message: sender does not match your session.")
+		return
+	}
+
+	if msg.Enc == "" && strings.HasPrefix(msg.Message, "/") {
+		if h.handleCommand(client, msg.Message) {
+			return
+		}
+	}
+
+	if h.isMuted(client.username) {
+		h.reply(client, "🔇 You are muted in this room; your message was not sent.")
+		return
+	}
+
+	if msg.ReplyTo != 0 {
+		if _, ok := h.lookup(msg.ReplyTo); !ok {
+			h.reply(client, fmt.Sprintf("❌ Rejected reply: no message #%d in this room.", msg.ReplyTo))
+			return
+		}
+	}
+
+	ttl, err := h.effectiveTTL(msg.TTL)
+	if err != nil {
+		h.reply(client, "❌ Rejected message: "+err.Error())
+		return
+	}
+
+	h.seq++
+	relayed := protocol.Message{
+		Type:      protocol.TypeMessage,
+		Message:   msg.Message,
+		Username:  client.username,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Seq:       h.seq,
+		ReplyTo:   msg.ReplyTo,
+		Enc:       msg.Enc,
+		TTL:       ttl,
+	}
+	data, err := json.Marshal(relayed)
+	if err != nil {
+		log.Printf("Encode failed in room %s: %v", h.pin, err)
+		return
+	}
+	// Self-destructing messages are never written to disk
+	if h.history != nil {
+		if relayed.TTL == 0 {
+			err = h.history.Append(relayed)
+		} else {
+			err = h.history.Skip(relayed.Seq)
+		}
+		if err != nil {
+			log.Printf("Storing history for room %s failed: %v", h.name, err)
+		}
+	}
+	if relayed.TTL == 0 {
+		h.remember(relayed)
+	}
+	h.touch(true)
+	h.deliver(data)
+}
+
+// Options configures a HubManager.
+type Options struct {
+	// OpenRooms lets authenticated users join PINs that have no room
+	// definition, creating the room on first join. Otherwise only defined
+	// rooms can be joined.
+	OpenRooms bool
+
+	// History stores every room's messages when set. HistoryReplay is how
+	// many of them a joining client is sent, at most maxReplay.
+	History       *history.Store
+	HistoryReplay int
+
+	// MaxFileSize caps file transfers in bytes in rooms that do not set
+	// their own cap; zero disables them.
+	MaxFileSize int64
+
+	// ClientIPHeader names the header a trusted reverse proxy puts the
+	// client address in, e.g. X-Forwarded-For. Failed logins are counted
+	// against that address instead of the proxy's.
+	ClientIPHeader string
+}
+
+type HubManager struct {
+	hubs map[string]*Hub
+
+	// online counts each user's connections per hub, for direct messages.
+	online   map[string]map[*Hub]int
+	sessions *auth.SessionManager
+	limiter  *addrLimiter
+	opts     Options
+	mu       sync.Mutex
+}
+
+func NewHubManager(sessions *auth.SessionManager, opts Options) *HubManager {
+	return &HubManager{
+		hubs:     make(map[string]*Hub),
+		online:   make(map[string]map[*Hub]int),
+		sessions: sessions,
+		limiter:  newAddrLimiter(),
+		opts:     opts,
+	}
+}
+
+func (m *HubManager) getHub(room rooms.Room, defined bool) (*Hub, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pin := room.ID
+	hub, exists := m.hubs[pin]
+	if exists {
+		select {
+		case <-hub.done:
+			// Shutting down; its goroutine will not remove a replacement
+			exists = false
+		default:
+		}
+	}
+	if !exists {
+		var err error
+		hub, err = newHub(room, defined, m)
+		if err != nil {
+			return nil, err
+		}
+		m.hubs[pin] = hub
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func(p string, h *Hub) {
+			h.run(ctx)
+			m.mu.Lock()
+			if m.hubs[p] == h {
+				delete(m.hubs, p)
+			}
+			m.mu.Unlock()
+			cancel()
+		}(pin, hub)
+	}
+	return hub, nil
+}
+
+// join registers client with the hub for room, creating the hub if needed.
+// A hub that shuts down as the client arrives is replaced by a fresh one.
+func (m *HubManager) join(room rooms.Room, defined bool, client *Client) error {
+	for {
+		hub, err := m.getHub(room, defined)
+		if err != nil {
+			return err
+		}
+		client.hub = hub
+		select {
+		case hub.register <- client:
+			return nil
+		case <-hub.done:
+		}
+	}
+}
+
+// forEachHub runs fn inside the goroutine of every live hub. It does not
+// wait, so it is safe to call from a hub's own goroutine.
+func (m *HubManager) forEachHub(fn func(h *Hub)) {
+	m.mu.Lock()
+	hubs := make([]*Hub, 0, len(m.hubs))
+	for _, h := range m.hubs {
+		hubs = append(hubs, h)
+	}
+	m.mu.Unlock()
+
+	for _, h := range hubs {
+		go func(h *Hub) {
+			select {
+			case h.exec <- func() { fn(h) }:
+			case <-h.done:
+			}
+		}(h)
+	}
+}
+
+// disconnectUser drops every connection username has in any room other
+// than skip, announcing it in the rooms it was dropped from.
+func (m *HubManager) disconnectUser(username, notice, announcement string, skip *Hub) {
+	m.forEachHub(func(h *Hub) {
+		if h != skip && h.dropUser(username, notice) && announcement != "" {
+			h.deliver(systemMessage(announcement))
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package hub
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+)
+
+// addrLimiter applies the account lockout policy to source addresses, so
+// guessing across many usernames from one host is throttled too.
+type addrLimiter struct {
+	mu      sync.Mutex
+	entries map[string]*addrEntry
+}
+
+// addrEntry counts an address's failed logins, split by the username they
+// were for, so a success only forgives the failures for that account.
+type addrEntry struct {
+	failures    int
+	accounts    map[string]int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// addrLockout describes a source address with failed logins on record.
+type addrLockout struct {
+	Addr        string
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+func newAddrLimiter() *addrLimiter {
+	return &addrLimiter{entries: make(map[string]*addrEntry)}
+}
+
+// remoteHost strips the port from an http.Request RemoteAddr.
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// clientAddr returns the address failed logins from r count against. Behind
+// a reverse proxy every connection comes from the proxy, so when header is
+// set the last address in it, the one the proxy added, is used instead;
+// earlier entries come from the client and can be forged.
+func clientAddr(r *http.Request, header string) string {
+	if header != "" {
+		if values := r.Header.Values(header); len(values) > 0 {
+			entries := strings.Split(values[len(values)-1], ",")
+			if addr := strings.TrimSpace(entries[len(entries)-1]); addr != "" {
+				return remoteHost(addr)
+			}
+		}
+		proxyWarning.Do(func() {
+			log.Printf("⚠️  Requests without a %s header are counted against the proxy address", header)
+		})
+	} else if r.Header.Get("X-Forwarded-For") != "" {
+		proxyWarning.Do(func() {
+			log.Printf("⚠️  Running behind a proxy? Failed logins are counted against its address; set --client-ip-header")
+		})
+	}
+	return remoteHost(r.RemoteAddr)
+}
+
+// proxyWarning logs the first request whose client address is unknown.
+var proxyWarning sync.Once
+
+// lockedUntil returns when addr may try again, if it is currently locked.
+func (l *addrLimiter) lockedUntil(addr string) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[addr]
+	if !ok || !time.Now().Before(entry.lockedUntil) {
+		return time.Time{}, false
+	}
+	return entry.lockedUntil, true
+}
+
+// failure counts a failed login for username from addr and locks the
+// address when the policy says so.
+func (l *addrLimiter) failure(addr, username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	policy := auth.CurrentLockoutPolicy()
+
+	// Forget addresses that have been quiet for longer than any lock
+	for a, e := range l.entries {
+		if now.Sub(e.lastFailure) > policy.MaxLockout && now.After(e.lockedUntil) {
+			delete(l.entries, a)
+		}
+	}
+
+	entry, ok := l.entries[addr]
+	if !ok {
+		entry = &addrEntry{accounts: make(map[string]int)}
+		l.entries[addr] = entry
+	}
+	entry.failures++
+	entry.accounts[username]++
+	entry.lastFailure = now
+	if lock := policy.LockDuration(entry.failures); lock > 0 {
+		entry.lockedUntil = now.Add(lock)
+	}
+}
+
+// success forgives the failures from addr for the account that just logged
+// in. Failures for other usernames still count, so an attacker cannot reset
+// the address by logging in to an account of their own between guesses.
+func (l *addrLimiter) success(addr, username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[addr]
+	if !ok {
+		return
+	}
+	entry.failures -= entry.accounts[username]
+	delete(entry.accounts, username)
+	if entry.failures == 0 && !time.Now().Before(entry.lockedUntil) {
+		delete(l.entries, addr)
+	}
+}
+
+// clear removes addr's record and reports whether there was one.
+func (l *addrLimiter) clear(addr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.entries[addr]
+	delete(l.entries, addr)
+	return ok
+}
+
+func (l *addrLimiter) list() []addrLockout {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lockouts := make([]addrLockout, 0, len(l.entries))
+	for addr, e := range l.entries {
+		lockouts = append(lockouts, addrLockout{
+			Addr:        addr,
+			Failures:    e.failures,
+			LastFailure: e.lastFailure,
+			LockedUntil: e.lockedUntil,
+		})
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].Addr < lockouts[j].Addr })
+	return lockouts
+}
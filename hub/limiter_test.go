@@ -0,0 +1,69 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddrLimiterSuccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []string
+		success  string
+		want     int
+	}{
+		{"forgives own failures", []string{"alice", "alice"}, "alice", 0},
+		{"keeps other accounts", []string{"bob", "carol", "alice"}, "alice", 2},
+		{"no failures for account", []string{"bob"}, "alice", 1},
+		{"no record", nil, "alice", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newAddrLimiter()
+			for _, username := range tt.failures {
+				l.failure("192.0.2.1", username)
+			}
+			l.success("192.0.2.1", tt.success)
+
+			got := 0
+			if entry, ok := l.entries["192.0.2.1"]; ok {
+				got = entry.failures
+			}
+			if got != tt.want {
+				t.Fatalf("failures after success = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		forwarded []string
+		want      string
+	}{
+		{"direct", "", nil, "192.0.2.1"},
+		{"header ignored unless configured", "", []string{"198.51.100.7"}, "192.0.2.1"},
+		{"proxied", "X-Forwarded-For", []string{"198.51.100.7"}, "198.51.100.7"},
+		{"forged entries before the proxy's", "X-Forwarded-For", []string{"203.0.113.9, 198.51.100.7"}, "198.51.100.7"},
+		{"last header line", "X-Forwarded-For", []string{"203.0.113.9", "198.51.100.7"}, "198.51.100.7"},
+		{"with port", "X-Forwarded-For", []string{"198.51.100.7:4711"}, "198.51.100.7"},
+		{"IPv6", "X-Forwarded-For", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"missing header", "X-Forwarded-For", nil, "192.0.2.1"},
+		{"empty entry", "X-Forwarded-For", []string{"198.51.100.7, "}, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			r.RemoteAddr = "192.0.2.1:5555"
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientAddr(r, tt.header); got != tt.want {
+				t.Fatalf("clientAddr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
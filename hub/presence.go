@@ -0,0 +1,65 @@
+package hub
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+)
+
+// member describes client for roster and presence frames. Only called
+// from run.
+func (h *Hub) member(client *Client) protocol.Member {
+	return protocol.Member{
+		Username:    client.username,
+		DisplayName: client.displayName,
+		Role:        h.roleOf(client.username),
+		Admin:       client.isAdmin,
+		JoinedAt:    client.joinedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// announcePresence tells the room that client's user joined or left. Only
+// called from run.
+func (h *Hub) announcePresence(client *Client, event string) {
+	data, err := json.Marshal(protocol.Message{
+		Type:      protocol.TypePresence,
+		Event:     event,
+		Members:   []protocol.Member{h.member(client)},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Printf("Encode failed in room %s: %v", h.pin, err)
+		return
+	}
+	h.deliver(data)
+}
+
+// who sends client the room roster, one entry per user, in join order.
+// Only called from run.
+func (h *Hub) who(client *Client) {
+	first := make(map[string]*Client)
+	for c := range h.clients {
+		if prev, ok := first[c.username]; !ok || c.joinedAt.Before(prev.joinedAt) {
+			first[c.username] = c
+		}
+	}
+	clients := make([]*Client, 0, len(first))
+	for _, c := range first {
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].joinedAt.Before(clients[j].joinedAt) })
+
+	members := make([]protocol.Member, 0, len(clients))
+	for _, c := range clients {
+		members = append(members, h.member(c))
+	}
+	data, err := json.Marshal(protocol.Message{Type: protocol.TypeRoster, Message: h.name, Members: members})
+	if err != nil {
+		log.Printf("Encode failed in room %s: %v", h.pin, err)
+		return
+	}
+	h.sendTo(client, data)
+}
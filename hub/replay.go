@@ -0,0 +1,57 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// maxReplay caps how many stored messages are replayed on join, so the
+// replay fits in the client's send buffer before live traffic starts.
+const maxReplay = 200
+
+// replay sends a joining client the stored messages it asked for: those
+// after client.since when it resumes, or the most recent ones. Only called
+// from run.
+func (h *Hub) replay(client *Client) {
+	if h.history == nil {
+		return
+	}
+
+	limit := h.manager.opts.HistoryReplay
+	if client.resume || limit > maxReplay {
+		limit = maxReplay
+	}
+	if limit <= 0 {
+		return
+	}
+
+	messages, skipped, err := h.history.Since(client.since, limit)
+	if err != nil {
+		log.Printf("Reading history for room %s failed: %v", h.name, err)
+		h.reply(client, "⚠️ Room history is unavailable.")
+		return
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	notice := fmt.Sprintf("📜 Replaying %d earlier messages", len(messages))
+	switch {
+	case skipped > 0 && client.resume:
+		// A resume must not lose messages silently when it cannot catch up
+		notice = fmt.Sprintf("⚠️ %d of the messages you missed were skipped; replaying the %d newest", skipped, len(messages))
+	case skipped > 0:
+		notice += " (older ones omitted)"
+	}
+	h.reply(client, notice)
+	for _, msg := range messages {
+		msg.History = true
+		data, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		h.sendTo(client, data)
+	}
+	h.reply(client, "📜 End of history")
+}
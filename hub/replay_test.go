@@ -0,0 +1,65 @@
+package hub
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/history"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/vault"
+)
+
+func TestReplay(t *testing.T) {
+	v, err := vault.New([]byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := history.NewStore(t.TempDir(), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, url := testServer(t, map[string]auth.User{"alice": {}, "bob": {}}, Options{OpenRooms: true, History: store, HistoryReplay: 2})
+
+	alice, _, err := join(t, manager, url, "ROOM1234", "alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceFrames := listen(alice)
+	for i := 1; i <= 4; i++ {
+		if err := alice.WriteJSON(protocol.Message{Type: protocol.TypeMessage, Message: fmt.Sprintf("message %d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drain(aliceFrames)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []uint64
+	}{
+		{"latest", "", []uint64{3, 4}},
+		{"resume from the start", "&since=0", []uint64{1, 2, 3, 4}},
+		{"resume", "&since=2", []uint64{3, 4}},
+		{"caught up", "&since=4", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The query rides along with the PIN
+			bob, _, err := join(t, manager, url, "ROOM1234"+tt.query, "bob", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []uint64
+			for _, msg := range drain(listen(bob)) {
+				if msg.History {
+					got = append(got, msg.Seq)
+				}
+			}
+			bob.Close()
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package hub
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+// Room roles, ordered so a higher rank may moderate a lower one.
+const (
+	rankMember = iota
+	rankModerator
+	rankOwner
+)
+
+func (h *Hub) rank(username string) int {
+	switch {
+	case username == h.owner:
+		return rankOwner
+	case h.moderators[username]:
+		return rankModerator
+	default:
+		return rankMember
+	}
+}
+
+func (h *Hub) roleOf(username string) string {
+	switch h.rank(username) {
+	case rankOwner:
+		return "owner"
+	case rankModerator:
+		return "moderator"
+	default:
+		return "member"
+	}
+}
+
+// present reports whether username has a connection in the room. Only
+// called from run.
+func (h *Hub) present(username string) bool {
+	for client := range h.clients {
+		if client.username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// saveRoles writes the room's roles back to its definition. Ad-hoc rooms
+// keep them only while the hub lives.
+func (h *Hub) saveRoles() {
+	if !h.defined {
+		return
+	}
+	moderators := make([]string, 0, len(h.moderators))
+	for username := range h.moderators {
+		moderators = append(moderators, username)
+	}
+	sort.Strings(moderators)
+	if err := rooms.SetRoles(h.pin, h.roomOwner, moderators); err != nil {
+		log.Printf("Saving roles for room %s failed: %v", h.name, err)
+	}
+}
+
+// setOwner makes username the owner and announces it. Only called from run.
+func (h *Hub) setOwner(username string) {
+	h.owner = username
+	h.roomOwner = username
+	delete(h.moderators, username)
+	h.saveRoles()
+	h.deliver(systemMessage(fmt.Sprintf("👑 %s is now the owner of room %s", username, h.name)))
+}
+
+// transferOwnership hands the room to the longest-present moderator, or
+// failing that the longest-present member. An empty room keeps its owner.
+// In a defined room the new owner only stands in: nothing is saved, and the
+// saved owner takes the room back when they return, so a dropped connection
+// does not give their room away. Only called from run.
+func (h *Hub) transferOwnership() {
+	var next *Client
+	for client := range h.clients {
+		if next == nil {
+			next = client
+			continue
+		}
+		nextMod, clientMod := h.moderators[next.username], h.moderators[client.username]
+		if clientMod != nextMod {
+			if clientMod {
+				next = client
+			}
+			continue
+		}
+		if client.joinedAt.Before(next.joinedAt) {
+			next = client
+		}
+	}
+	switch {
+	case next == nil:
+	case h.defined:
+		h.owner = next.username
+		h.deliver(systemMessage(fmt.Sprintf("👑 %s stands in as owner of room %s while %s is away", next.username, h.name, h.roomOwner)))
+	default:
+		h.setOwner(next.username)
+	}
+}
+
+// restoreOwner hands a defined room back to its saved owner on their
+// return. Only called from run.
+func (h *Hub) restoreOwner() {
+	h.owner = h.roomOwner
+	h.deliver(systemMessage(fmt.Sprintf("👑 %s is back as the owner of room %s", h.roomOwner, h.name)))
+}
+
+// requireRoomOwner refuses client unless it is the room's saved owner, so a
+// stand-in cannot rewrite settings the owner saved. what names the setting
+// for the refusal. Only called from run.
+func (h *Hub) requireRoomOwner(client *Client, what string) bool {
+	if h.rank(client.username) != rankOwner {
+		h.reply(client, fmt.Sprintf("❌ Access denied. Only the room owner can change %s.", what))
+		return false
+	}
+	if h.owner != h.roomOwner {
+		h.reply(client, fmt.Sprintf("❌ Only %s can change %s; you are standing in while they are away.", h.roomOwner, what))
+		return false
+	}
+	return true
+}
+
+// greetRole tells a joining client about its room role. Only called from run.
+func (h *Hub) greetRole(client *Client) {
+	switch h.rank(client.username) {
+	case rankOwner:
+		h.reply(client, "👑 You own this room. Use /mod <username> to appoint moderators, and /kick, /ban or /mute to moderate.")
+	case rankModerator:
+		h.reply(client, "🛡️ You are a moderator in this room. Use /kick, /ban or /mute to moderate.")
+	}
+	if client.isAdmin {
+		h.reply(client, "🔑 Admin privileges enabled. Account commands: /disable, /enable, /lock, /deluser.")
+	}
+}
+
+// manageRole handles /mod, /unmod and /owner, which only the room owner
+// may use.
+func (h *Hub) manageRole(client *Client, command, target string) {
+	if !h.requireRoomOwner(client, "roles") {
+		return
+	}
+	if target == "" {
+		h.reply(client, fmt.Sprintf("❌ Usage: %s <username>", command))
+		return
+	}
+	if target == client.username {
+		h.reply(client, "❌ You cannot change your own role.")
+		return
+	}
+
+	switch command {
+	case "/mod":
+		if !h.present(target) {
+			h.reply(client, fmt.Sprintf("❌ User '%s' not found in room.", target))
+			return
+		}
+		if h.moderators[target] {
+			h.reply(client, fmt.Sprintf("❌ %s is already a moderator.", target))
+			return
+		}
+		h.moderators[target] = true
+		h.saveRoles()
+		h.deliver(systemMessage(fmt.Sprintf("🛡️ %s is now a moderator (appointed by %s)", target, client.username)))
+	case "/unmod":
+		if !h.moderators[target] {
+			h.reply(client, fmt.Sprintf("❌ %s is not a moderator.", target))
+			return
+		}
+		delete(h.moderators, target)
+		h.saveRoles()
+		h.deliver(systemMessage(fmt.Sprintf("🛡️ %s is no longer a moderator", target)))
+	case "/owner":
+		if !h.present(target) {
+			h.reply(client, fmt.Sprintf("❌ User '%s' not found in room.", target))
+			return
+		}
+		// The previous owner stays on as a moderator
+		h.moderators[client.username] = true
+		h.setOwner(target)
+	}
+}
@@ -0,0 +1,77 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+func TestStandInCannotChangeRoomSettings(t *testing.T) {
+	users := map[string]auth.User{
+		"root":  {IsAdmin: true},
+		"alice": {},
+		"bob":   {},
+		"carol": {},
+	}
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{"topic", "/topic hijacked"},
+		{"motd", "/motd hijacked"},
+		{"ttl", "/ttl 60"},
+		{"roles", "/mod carol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, url := testServer(t, users, Options{})
+			if err := rooms.CreateRoom("root", true, "ops", "1234", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			id := e2e.RoomID("1234")
+			if err := rooms.SetRoles(id, "alice", nil); err != nil {
+				t.Fatal(err)
+			}
+
+			alice, _, err := join(t, manager, url, "1234", "alice", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			drain(listen(alice))
+			bob, _, err := join(t, manager, url, "1234", "bob", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			carol, _, err := join(t, manager, url, "1234", "carol", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			drain(listen(carol))
+			bobFrames := listen(bob)
+			drain(bobFrames)
+
+			alice.Close()
+			if !said(drain(bobFrames), "bob stands in as owner") {
+				t.Fatal("bob did not stand in for alice")
+			}
+			if err := bob.WriteJSON(protocol.Message{Type: protocol.TypeMessage, Message: tt.command}); err != nil {
+				t.Fatal(err)
+			}
+			if !said(drain(bobFrames), "Only alice can change") {
+				t.Fatalf("%s was not refused", tt.command)
+			}
+
+			db, err := rooms.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			room := db.Rooms[id]
+			if room.Topic != "" || room.MOTD != "" || room.DefaultTTL != 0 || len(room.Moderators) != 0 || room.Owner != "alice" {
+				t.Fatalf("saved room changed: %+v", room)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+	"github.com/gorilla/websocket"
+)
+
+// --- Origin check ---
+func allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // same-origin or CLI
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	originHost := u.Host
+	reqHost := r.Host
+
+	if strings.Contains(originHost, "localhost") || strings.Contains(originHost, "127.0.0.1") {
+		return true
+	}
+
+	if strings.EqualFold(originHost, reqHost) {
+		return true
+	}
+
+	// Allow Render subdomains if needed
+	if strings.HasSuffix(originHost, ".onrender.com") || originHost == "onrender.com" {
+		return true
+	}
+
+	return false
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
+	EnableCompression: true,
+	Subprotocols:      protocol.Subprotocols(),
+	CheckOrigin: func(r *http.Request) bool {
+		ok := allowOrigin(r)
+		log.Printf("Incoming WebSocket from Origin=%q Host=%q -> allow=%v", r.Header.Get("Origin"), r.Host, ok)
+		return ok
+	},
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// negotiateVersion picks the protocol version for a connection: the first
+// supported one the client offers, newest first. The upgrader selects the
+// same subprotocol. Clients that predate negotiation offer none and speak
+// version 1.
+func negotiateVersion(r *http.Request) (int, error) {
+	offered := websocket.Subprotocols(r)
+	if len(offered) == 0 {
+		offered = []string{protocol.Subprotocol(1)}
+	}
+	for _, name := range offered {
+		if version, err := protocol.ParseSubprotocol(name); err == nil {
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("no supported protocol version offered; this server supports %s", strings.Join(protocol.Subprotocols(), ", "))
+}
+
+// ServeWs upgrades an HTTP request to a chat connection in the room named by
+// the pin query parameter, authenticating it first.
+func ServeWs(manager *HubManager, w http.ResponseWriter, r *http.Request) {
+	// Without a PIN the connection only runs the login handshake, which is
+	// how clients change passwords without joining a room.
+	pin := r.URL.Query().Get("pin")
+
+	// since resumes history after a sequence number the client has seen
+	var since uint64
+	resume := r.URL.Query().Has("since")
+	if resume {
+		parsed, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid since parameter", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	version, err := negotiateVersion(r)
+	if err != nil {
+		log.Printf("Rejected WebSocket from %s: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusUpgradeRequired)
+		return
+	}
+
+	// A previously issued session token may be presented up front;
+	// otherwise the client must complete the login handshake.
+	var session auth.Session
+	var room rooms.Room
+	var defined bool
+	token := bearerToken(r)
+	if token != "" && pin == "" {
+		http.Error(w, "PIN required", http.StatusBadRequest)
+		return
+	}
+	if token != "" {
+		verified, err := manager.sessions.Verify(token)
+		if err != nil {
+			log.Printf("Rejected WebSocket from %s: %v", r.RemoteAddr, err)
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		// Tokens outlive account changes, so re-check the account itself:
+		// its role comes from the account, and an admin reset has to be
+		// completed through the login handshake.
+		user, err := auth.CheckAccount(verified.Username)
+		if err != nil {
+			log.Printf("Rejected session for %s from %s: %v", verified.Username, r.RemoteAddr, err)
+			http.Error(w, "Login refused: "+err.Error(), http.StatusForbidden)
+			return
+		}
+		if user.MustChangePassword {
+			log.Printf("Rejected session for %s from %s: password change pending", verified.Username, r.RemoteAddr)
+			http.Error(w, "Password change required; log in again", http.StatusUnauthorized)
+			return
+		}
+		verified.IsAdmin = user.IsAdmin
+		session = verified
+
+		room, defined, err = manager.authorize(pin, session)
+		if err != nil {
+			log.Printf("Rejected %s from room %s: %v", session.Username, pin, err)
+			status := http.StatusForbidden
+			if errors.Is(err, errRoomNotFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	addr := clientAddr(r, manager.opts.ClientIPHeader)
+	if token == "" {
+		if until, locked := manager.limiter.lockedUntil(addr); locked {
+			log.Printf("Rejected login from locked address %s", addr)
+			http.Error(w, "Too many failed logins; try again after "+until.UTC().Format(time.RFC3339), http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	if token == "" {
+		verified, err := serverHandshake(conn, manager, addr, version)
+		if err != nil {
+			log.Printf("Login handshake from %s failed: %v", r.RemoteAddr, err)
+			_ = conn.Close()
+			return
+		}
+		session = verified
+		if pin == "" {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "login only"))
+			_ = conn.Close()
+			return
+		}
+
+		room, defined, err = manager.authorize(pin, session)
+		if err != nil {
+			log.Printf("Rejected %s from room %s: %v", session.Username, pin, err)
+			denyRoom(conn, err)
+			return
+		}
+	}
+	username := session.Username
+	isAdmin := session.IsAdmin
+	displayName := username
+	if user, err := auth.CheckAccount(username); err == nil && user.DisplayName != "" {
+		displayName = user.DisplayName
+	}
+
+	log.Printf("New WebSocket connection for room %s, User: %s, Admin: %v", room.Name, username, isAdmin)
+
+	client := &Client{
+		conn:        conn,
+		send:        make(chan []byte, 256),
+		username:    username,
+		displayName: displayName,
+		isAdmin:     isAdmin,
+		joinedAt:    time.Now(),
+		version:     version,
+		since:       since,
+		resume:      resume,
+	}
+	if err := manager.join(room, defined, client); err != nil {
+		log.Printf("Room setup failed: %v", err)
+		_ = conn.Close()
+		return
+	}
+
+	go client.writePump()
+	client.readPump()
+}
+
+// denyRoom tells an authenticated client why it may not join and closes
+// the connection.
+func denyRoom(conn *websocket.Conn, reason error) {
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	data, _ := json.Marshal(protocol.Message{Type: protocol.TypeRoomDenied, Message: reason.Error()})
+	_ = conn.WriteMessage(websocket.TextMessage, data)
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "room access denied"))
+	_ = conn.Close()
+}
@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/auth"
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// testServer serves manager over HTTP from a temporary directory holding
+// users, and returns the WebSocket URL.
+func testServer(t *testing.T, users map[string]auth.User, opts Options) (*HubManager, string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := auth.SaveUsers(auth.UserDatabase{Users: users}); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := auth.NewSessionManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewHubManager(sessions, opts)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(manager, w, r)
+	}))
+	t.Cleanup(server.Close)
+	return manager, "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+// join connects to room pin with a session token issued for username.
+func join(t *testing.T, manager *HubManager, url, pin, username string, isAdmin bool) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	token, _, err := manager.sessions.Issue(username, isAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialer := websocket.Dialer{Subprotocols: protocol.Subprotocols()}
+	conn, resp, err := dialer.Dial(url+"?pin="+pin, http.Header{"Authorization": {"Bearer " + token}})
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+// listen reads frames from conn until it closes. Reads run on their own
+// goroutine because a read deadline would break the connection.
+func listen(conn *websocket.Conn) <-chan protocol.Message {
+	frames := make(chan protocol.Message, 64)
+	go func() {
+		defer close(frames)
+		for {
+			var msg protocol.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			frames <- msg
+		}
+	}()
+	return frames
+}
+
+// drain collects frames until the connection has been quiet for a moment.
+func drain(frames <-chan protocol.Message) []protocol.Message {
+	var got []protocol.Message
+	for {
+		select {
+		case msg, ok := <-frames:
+			if !ok {
+				return got
+			}
+			got = append(got, msg)
+		case <-time.After(300 * time.Millisecond):
+			return got
+		}
+	}
+}
+
+// said reports whether a frame's text contains text.
+func said(frames []protocol.Message, text string) bool {
+	for _, msg := range frames {
+		if strings.Contains(msg.Message, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTokenLoginRechecksAccount(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       auth.User
+		tokenAdmin bool
+		wantStatus int
+		wantAdmin  bool
+	}{
+		{"member", auth.User{}, false, 0, false},
+		{"admin", auth.User{IsAdmin: true}, true, 0, true},
+		{"demoted admin", auth.User{}, true, 0, false},
+		{"promoted member", auth.User{IsAdmin: true}, false, 0, true},
+		{"pending reset", auth.User{MustChangePassword: true}, false, http.StatusUnauthorized, false},
+		{"disabled", auth.User{Disabled: true}, false, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, url := testServer(t, map[string]auth.User{"alice": tt.user}, Options{OpenRooms: true})
+			conn, resp, err := join(t, manager, url, "ROOM1234", "alice", tt.tokenAdmin)
+			if tt.wantStatus != 0 {
+				if err == nil || resp == nil || resp.StatusCode != tt.wantStatus {
+					t.Fatalf("join() = %v, want HTTP %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := said(drain(listen(conn)), "Admin privileges enabled"); got != tt.wantAdmin {
+				t.Fatalf("admin greeting = %v, want %v", got, tt.wantAdmin)
+			}
+		})
+	}
+}
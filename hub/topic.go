@@ -0,0 +1,100 @@
+package hub
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+// Limits keep the join greeting well inside a client's frame size.
+const (
+	maxTopicLength = 200
+	maxMOTDLength  = 2000
+)
+
+// clearTopic is the argument that removes a topic or MOTD.
+const clearTopic = "clear"
+
+// greetTopic shows a joining client the room topic and MOTD. Only called
+// from run.
+func (h *Hub) greetTopic(client *Client) {
+	if h.topic != "" {
+		h.reply(client, "📌 Topic: "+h.topic)
+	}
+	if h.motd != "" {
+		h.reply(client, "📋 Message of the day:\n"+h.motd)
+	}
+}
+
+// setTopic handles /topic: anyone may read it, the owner sets it.
+func (h *Hub) setTopic(client *Client, args string) {
+	if args == "" {
+		if h.topic == "" {
+			h.reply(client, "📌 No topic set. Usage: /topic <text|clear>")
+		} else {
+			h.reply(client, "📌 Topic: "+h.topic)
+		}
+		return
+	}
+	if !h.requireRoomOwner(client, "the topic") {
+		return
+	}
+	if len(args) > maxTopicLength {
+		h.reply(client, fmt.Sprintf("❌ The topic is limited to %d characters.", maxTopicLength))
+		return
+	}
+
+	topic := args
+	if topic == clearTopic {
+		topic = ""
+	}
+	h.topic = topic
+	if h.defined {
+		if err := rooms.SetTopic(h.pin, topic); err != nil {
+			log.Printf("Saving topic for room %s failed: %v", h.name, err)
+		}
+	}
+	if topic == "" {
+		h.deliver(systemMessage(fmt.Sprintf("📌 %s cleared the topic", client.username)))
+	} else {
+		h.deliver(systemMessage(fmt.Sprintf("📌 %s set the topic: %s", client.username, topic)))
+	}
+}
+
+// setMOTD handles /motd. Lines are separated with a literal \n, since chat
+// input is one line.
+func (h *Hub) setMOTD(client *Client, args string) {
+	if args == "" {
+		if h.motd == "" {
+			h.reply(client, `📋 No message of the day set. Usage: /motd <line\nline...|clear>`)
+		} else {
+			h.reply(client, "📋 Message of the day:\n"+h.motd)
+		}
+		return
+	}
+	if !h.requireRoomOwner(client, "the message of the day") {
+		return
+	}
+
+	motd := strings.ReplaceAll(args, `\n`, "\n")
+	if motd == clearTopic {
+		motd = ""
+	}
+	if len(motd) > maxMOTDLength {
+		h.reply(client, fmt.Sprintf("❌ The message of the day is limited to %d characters.", maxMOTDLength))
+		return
+	}
+	h.motd = motd
+	if h.defined {
+		if err := rooms.SetMOTD(h.pin, motd); err != nil {
+			log.Printf("Saving message of the day for room %s failed: %v", h.name, err)
+		}
+	}
+	if motd == "" {
+		h.deliver(systemMessage(fmt.Sprintf("📋 %s cleared the message of the day", client.username)))
+	} else {
+		h.deliver(systemMessage(fmt.Sprintf("📋 %s updated the message of the day:\n%s", client.username, motd)))
+	}
+}
@@ -0,0 +1,56 @@
+package hub
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/protocol"
+	"github.com/EJ-Edwards/Secuchat-CLI/rooms"
+)
+
+// effectiveTTL applies the room's default timer to a message's own. A room
+// default is a ceiling: messages may burn sooner, never later.
+func (h *Hub) effectiveTTL(requested int) (int, error) {
+	if requested < 0 || requested > protocol.MaxTTL {
+		return 0, fmt.Errorf("ttl must be between 1 and %d seconds", protocol.MaxTTL)
+	}
+	if h.defaultTTL > 0 && (requested == 0 || requested > h.defaultTTL) {
+		return h.defaultTTL, nil
+	}
+	return requested, nil
+}
+
+// setDefaultTTL handles /ttl, which the room owner uses to make every
+// message in the room self-destruct.
+func (h *Hub) setDefaultTTL(client *Client, args string) {
+	if args == "" {
+		if h.defaultTTL == 0 {
+			h.reply(client, "🔥 Messages in this room do not self-destruct. Usage: /ttl <seconds|duration|off>")
+		} else {
+			h.reply(client, fmt.Sprintf("🔥 Messages in this room self-destruct after %s.", time.Duration(h.defaultTTL)*time.Second))
+		}
+		return
+	}
+	if !h.requireRoomOwner(client, "the message timer") {
+		return
+	}
+
+	ttl, err := protocol.ParseTTL(args)
+	if err != nil {
+		h.reply(client, "❌ "+err.Error())
+		return
+	}
+
+	h.defaultTTL = ttl
+	if h.defined {
+		if err := rooms.SetDefaultTTL(h.pin, ttl); err != nil {
+			log.Printf("Saving message timer for room %s failed: %v", h.name, err)
+		}
+	}
+	if ttl == 0 {
+		h.deliver(systemMessage(fmt.Sprintf("🔥 %s turned off the room message timer", client.username)))
+	} else {
+		h.deliver(systemMessage(fmt.Sprintf("🔥 %s set messages in this room to self-destruct after %s", client.username, time.Duration(ttl)*time.Second)))
+	}
+}
@@ -0,0 +1,238 @@
+// Package protocol defines the JSON frames exchanged between Secuchat
+// clients and the server, and the protocol version they are negotiated
+// under (see version.go).
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Chat frame types.
+const (
+	TypeSystem  = "system"
+	TypeMessage = "message"
+	TypePing    = "ping"
+	TypePong    = "pong"
+
+	// TypeDM is a direct message to the user named in To, who may be in
+	// any room. The sender gets a copy back once it is delivered.
+	TypeDM = "dm"
+
+	// TypeEdit replaces the body of the chat message numbered Seq, and
+	// TypeDelete retracts it. Clients send them with Seq and, for edits,
+	// the new body; the server relays them with the author in Username and
+	// whoever made the change in By.
+	TypeEdit   = "edit"
+	TypeDelete = "delete"
+
+	// File transfer: the sender offers a file, uploads its chunks, and once
+	// all have arrived the server announces the offer to the room. Members
+	// then fetch chunks FileWindow at a time with file_accept, asking for
+	// the next window once the last chunk of the previous one arrives.
+	//
+	//	client -> file_offer  {"msg":name,"enc","file":{"id","size","chunks","sha256"}}
+	//	client -> file_chunk  {"msg":data,"enc","file":{"id","index"}}
+	//	server -> file_offer  {"user","ts","msg":name,"enc","file":{...}}
+	//	client -> file_accept {"file":{"id","index"}}
+	//	server -> file_chunk  {"user","msg":data,"enc","file":{"id","index"}}
+	//
+	// SHA256 is the hex digest of the whole file, checked by the receiver.
+	// In encrypted rooms the name, digest and data are sealed with the room
+	// key, so the server learns only the size.
+	TypeFileOffer  = "file_offer"
+	TypeFileChunk  = "file_chunk"
+	TypeFileAccept = "file_accept"
+
+	// TypeRoster answers /who with every member of the room. TypePresence
+	// announces a member's first connection joining ("join") or last one
+	// leaving ("leave"), so clients can keep the list current.
+	TypeRoster   = "roster"
+	TypePresence = "presence"
+
+	// TypeRoomInfo is the first frame a client receives after joining. It
+	// carries the room's key salt for end-to-end encryption and confirms
+	// the negotiated protocol version.
+	TypeRoomInfo = "room_info"
+
+	// TypeRoomDenied replaces room_info when the user may not join the
+	// room; the server closes the connection after sending it.
+	TypeRoomDenied = "room_denied"
+)
+
+// Login handshake frame types, in the order they are exchanged:
+//
+//	client -> auth_hello     {"user"}
+//	server -> auth_challenge {"v","nonce","salt"}
+//	client -> auth_response  {"proof","change_password"}
+//	server -> auth_ok        {"user","admin","token","exp"} | auth_failed {"msg"}
+//
+// When the account has a pending admin reset, or the client asked for it,
+// a password change runs between auth_response and auth_ok:
+//
+//	server -> auth_change_required {"msg"}
+//	client -> auth_change          {"salt","verifier","proof"}
+//
+// Accounts with two-factor authentication are asked for a TOTP code after
+// the proof, or shown a new secret to enroll when 2FA is required for them:
+//
+//	server -> auth_totp_required {"msg"} | auth_totp_enroll {"msg","uri"}
+//	client -> auth_totp          {"code"}
+const (
+	TypeAuthHello     = "auth_hello"
+	TypeAuthChallenge = "auth_challenge"
+	TypeAuthResponse  = "auth_response"
+	TypeAuthOK        = "auth_ok"
+	TypeAuthFailed    = "auth_failed"
+
+	TypeAuthChangeRequired = "auth_change_required"
+	TypeAuthChange         = "auth_change"
+
+	TypeAuthTOTPRequired = "auth_totp_required"
+	TypeAuthTOTPEnroll   = "auth_totp_enroll"
+	TypeAuthTOTP         = "auth_totp"
+)
+
+// Message is the envelope of every chat frame. Fields beyond Type are set
+// as the frame type needs them.
+type Message struct {
+	Type      string `json:"type"`
+	Version   int    `json:"v,omitempty"`
+	Message   string `json:"msg,omitempty"`
+	Username  string `json:"user,omitempty"`
+	To        string `json:"to,omitempty"`
+	Timestamp string `json:"ts,omitempty"`
+
+	// Seq is assigned by the server to every relayed chat message and
+	// increases by one per message within a room. It is the message's ID
+	// for edits and deletes. In room_info it is the room's newest number, so
+	// a reconnecting client can tell the room was recreated.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// ReplyTo is the ID of the message this one answers, which must exist
+	// in the same room. Replies form threads.
+	ReplyTo uint64 `json:"reply_to,omitempty"`
+
+	// Edited marks a message whose body was replaced by By. End-to-end
+	// encrypted edits are sealed by the editor, so they open under By.
+	Edited bool   `json:"edited,omitempty"`
+	By     string `json:"by,omitempty"`
+
+	// Enc names the cipher when Message holds an end-to-end encrypted body.
+	Enc  string `json:"enc,omitempty"`
+	Salt string `json:"salt,omitempty"`
+
+	// History marks a stored message replayed to a joining client.
+	History bool `json:"history,omitempty"`
+
+	// TTL makes a message self-destruct: clients remove it this many
+	// seconds after receiving it, and the server never stores it.
+	TTL int `json:"ttl,omitempty"`
+
+	// File describes a file transfer in file frames. In room_info,
+	// MaxFileSize is the room's cap in bytes; zero disables transfers.
+	File        *FileInfo `json:"file,omitempty"`
+	MaxFileSize int64     `json:"max_file_size,omitempty"`
+
+	// Members lists the room for roster frames; presence frames carry the
+	// one member that joined or left, and the Event.
+	Members []Member `json:"members,omitempty"`
+	Event   string   `json:"event,omitempty"`
+}
+
+// FileChunkSize is the plaintext size of every file chunk but the last;
+// sealed and encoded it still fits in one frame. FileWindow is how many
+// chunks the server sends per file_accept.
+const (
+	FileChunkSize = 4096
+	FileWindow    = 16
+)
+
+// FileInfo identifies a file transfer, or one chunk of it by Index. The
+// sender picks the ID; it must be unique within the room.
+type FileInfo struct {
+	ID     string `json:"id"`
+	Size   int64  `json:"size,omitempty"`
+	Chunks int    `json:"chunks,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	Index  int    `json:"index,omitempty"`
+}
+
+// FileChunks returns how many chunks a file of size bytes is sent in.
+func FileChunks(size int64) int {
+	return int((size + FileChunkSize - 1) / FileChunkSize)
+}
+
+// FormatSize renders a byte count for people, e.g. "1.5 MiB".
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// Presence events.
+const (
+	PresenceJoin  = "join"
+	PresenceLeave = "leave"
+)
+
+// Member describes one user in a room.
+type Member struct {
+	Username    string `json:"user"`
+	DisplayName string `json:"display_name,omitempty"`
+	Role        string `json:"role"`
+	Admin       bool   `json:"admin,omitempty"`
+	JoinedAt    string `json:"joined_at"`
+}
+
+// MaxTTL is the longest self-destruct timer the server accepts, in seconds.
+const MaxTTL = 24 * 60 * 60
+
+// ParseTTL reads a self-destruct timer given as seconds ("60") or as a
+// duration ("5m") and returns it in seconds. "off" and "0" mean no timer.
+func ParseTTL(value string) (int, error) {
+	if value == "off" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if seconds, convErr := strconv.Atoi(value); convErr == nil {
+		ttl, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid timer %q; use seconds or a duration like 5m", value)
+	}
+	if ttl < 0 || ttl > MaxTTL*time.Second || (ttl > 0 && ttl < time.Second) {
+		return 0, fmt.Errorf("timer must be between 1s and %s", MaxTTL*time.Second)
+	}
+	return int(ttl / time.Second), nil
+}
+
+// AuthMessage carries the login handshake that runs on a fresh WebSocket
+// before the client is registered with a hub.
+type AuthMessage struct {
+	Type      string    `json:"type"`
+	Version   int       `json:"v,omitempty"`
+	Username  string    `json:"user,omitempty"`
+	Nonce     string    `json:"nonce,omitempty"`
+	Salt      string    `json:"salt,omitempty"`
+	Proof     string    `json:"proof,omitempty"`
+	Verifier  string    `json:"verifier,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	IsAdmin   bool      `json:"admin,omitempty"`
+	ExpiresAt time.Time `json:"exp,omitempty"`
+	Message   string    `json:"msg,omitempty"`
+
+	ChangePassword bool `json:"change_password,omitempty"`
+
+	// Code is a TOTP verification code; URI is the otpauth:// enrollment URI.
+	Code string `json:"code,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
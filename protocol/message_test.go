@@ -0,0 +1,36 @@
+package protocol
+
+import "testing"
+
+func TestParseTTL(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"off", 0, false},
+		{"0", 0, false},
+		{"30", 30, false},
+		{"30s", 30, false},
+		{"5m", 300, false},
+		{"1h30m", 5400, false},
+		{"24h", MaxTTL, false},
+		{"25h", 0, true},
+		{"500ms", 0, true},
+		{"-5", 0, true},
+		{"-1m", 0, true},
+		{"soon", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTTL(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTTL(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseTTL(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
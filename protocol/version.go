@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Protocol versions this build speaks. A version is added whenever a frame
+// changes incompatibly. Version 2 replaced version 1's HMAC login proof with
+// a signature, which version 1 clients cannot produce.
+const (
+	Version    = 2
+	MinVersion = 2
+)
+
+// subprotocolPrefix names versions in the Sec-WebSocket-Protocol header,
+// where the version is negotiated when the connection opens.
+const subprotocolPrefix = "secuchat.v"
+
+// Subprotocol returns the WebSocket subprotocol name of version v.
+func Subprotocol(v int) string {
+	return subprotocolPrefix + strconv.Itoa(v)
+}
+
+// Subprotocols lists the supported versions, newest first, as offered by
+// clients and accepted by the server.
+func Subprotocols() []string {
+	list := make([]string, 0, Version-MinVersion+1)
+	for v := Version; v >= MinVersion; v-- {
+		list = append(list, Subprotocol(v))
+	}
+	return list
+}
+
+// ParseSubprotocol returns the version named by a WebSocket subprotocol,
+// failing for names that are not a supported Secuchat version.
+func ParseSubprotocol(name string) (int, error) {
+	number, ok := strings.CutPrefix(name, subprotocolPrefix)
+	v, err := strconv.Atoi(number)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("unknown subprotocol %q", name)
+	}
+	if v < MinVersion || v > Version {
+		return 0, fmt.Errorf("unsupported protocol version %d (supported %d-%d)", v, MinVersion, Version)
+	}
+	return v, nil
+}
@@ -0,0 +1,38 @@
+package protocol
+
+import "testing"
+
+func TestParseSubprotocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    int
+		wantErr bool
+	}{
+		{Subprotocol(Version), Version, false},
+		{Subprotocol(MinVersion), MinVersion, false},
+		{Subprotocol(MinVersion - 1), 0, true},
+		{Subprotocol(Version + 1), 0, true},
+		{"secuchat.v", 0, true},
+		{"secuchat.vx", 0, true},
+		{"chat.v2", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSubprotocol(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSubprotocol(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseSubprotocol(%q) = %d, want %d", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubprotocols(t *testing.T) {
+	list := Subprotocols()
+	if len(list) != Version-MinVersion+1 || list[0] != Subprotocol(Version) {
+		t.Fatalf("Subprotocols() = %v, want newest first from %d to %d", list, Version, MinVersion)
+	}
+}
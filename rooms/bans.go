@@ -0,0 +1,218 @@
+package rooms
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Ban keeps a user out of one room, or out of every room when it is a
+// global ban. A zero Until never expires.
+type Ban struct {
+	Username string    `json:"user"`
+	Reason   string    `json:"reason,omitempty"`
+	By       string    `json:"by"`
+	At       time.Time `json:"at"`
+	Until    time.Time `json:"until,omitempty"`
+}
+
+// Active reports whether the ban still applies at now.
+func (b Ban) Active(now time.Time) bool {
+	return b.Until.IsZero() || now.Before(b.Until)
+}
+
+// Terms describes how long the ban lasts and why, for notices.
+func (b Ban) Terms() string {
+	terms := "permanently"
+	if !b.Until.IsZero() {
+		terms = "until " + b.Until.Local().Format("2006-01-02 15:04")
+	}
+	if b.Reason != "" {
+		terms += " (" + b.Reason + ")"
+	}
+	return terms
+}
+
+// Banned returns the ban that keeps username out of room id, checking
+// global bans first. Ad-hoc rooms are banned from by their PIN.
+func (db Database) Banned(id, username string, now time.Time) (Ban, bool) {
+	for _, ban := range db.GlobalBans {
+		if ban.Username == username && ban.Active(now) {
+			return ban, true
+		}
+	}
+	for _, ban := range db.Bans[id] {
+		if ban.Username == username && ban.Active(now) {
+			return ban, true
+		}
+	}
+	return Ban{}, false
+}
+
+// withBan replaces any ban of the same user in list and drops expired ones.
+func withBan(list []Ban, ban Ban, now time.Time) []Ban {
+	kept := []Ban{ban}
+	for _, existing := range list {
+		if existing.Username != ban.Username && existing.Active(now) {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
+
+// withoutBan removes username from list and reports whether it was there.
+func withoutBan(list []Ban, username string) ([]Ban, bool) {
+	var kept []Ban
+	removed := false
+	for _, existing := range list {
+		if existing.Username == username {
+			removed = true
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	return kept, removed
+}
+
+// AddBan records ban for room id, or for every room when id is empty.
+func AddBan(id string, ban Ban) error {
+	now := time.Now()
+	return Update(func(db *Database) error {
+		if id == "" {
+			db.GlobalBans = withBan(db.GlobalBans, ban, now)
+			return nil
+		}
+		db.Bans[id] = withBan(db.Bans[id], ban, now)
+		return nil
+	})
+}
+
+// RemoveBan lifts username's ban from room id, or its global ban when id is
+// empty.
+func RemoveBan(id, username string) error {
+	return Update(func(db *Database) error {
+		var removed bool
+		if id == "" {
+			db.GlobalBans, removed = withoutBan(db.GlobalBans, username)
+		} else {
+			db.Bans[id], removed = withoutBan(db.Bans[id], username)
+			if len(db.Bans[id]) == 0 {
+				delete(db.Bans, id)
+			}
+		}
+		if !removed {
+			return fmt.Errorf("'%s' is not banned", username)
+		}
+		return nil
+	})
+}
+
+// ActiveBans returns the bans in force for room id, or the global bans when
+// id is empty, sorted by user.
+func ActiveBans(id string) ([]Ban, error) {
+	db, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	list := db.GlobalBans
+	if id != "" {
+		list = db.Bans[id]
+	}
+
+	now := time.Now()
+	var active []Ban
+	for _, ban := range list {
+		if ban.Active(now) {
+			active = append(active, ban)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Username < active[j].Username })
+	return active, nil
+}
+
+// BanUser bans target from the room called roomName, or from every room
+// when roomName is empty, for duration (zero is permanent).
+func BanUser(adminUsername string, isAdmin bool, roomName, target string, duration time.Duration, reason string) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can ban users here")
+	}
+	id, err := banScope(roomName)
+	if err != nil {
+		return err
+	}
+	ban := Ban{Username: target, Reason: reason, By: adminUsername, At: time.Now()}
+	if duration > 0 {
+		ban.Until = ban.At.Add(duration)
+	}
+	return AddBan(id, ban)
+}
+
+// UnbanUser lifts target's ban from the room called roomName, or its global
+// ban when roomName is empty.
+func UnbanUser(adminUsername string, isAdmin bool, roomName, target string) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can unban users here")
+	}
+	id, err := banScope(roomName)
+	if err != nil {
+		return err
+	}
+	return RemoveBan(id, target)
+}
+
+// banScope maps a room name to the ID its bans are kept under.
+func banScope(roomName string) (string, error) {
+	if roomName == "" {
+		return "", nil
+	}
+	db, err := Load()
+	if err != nil {
+		return "", err
+	}
+	room, exists := db.ByName(roomName)
+	if !exists {
+		return "", fmt.Errorf("room '%s' not found", roomName)
+	}
+	return room.ID, nil
+}
+
+// ListBans prints global bans and the bans of every defined room.
+func ListBans() error {
+	db, err := Load()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🔨 Bans")
+	fmt.Println("=======")
+	now := time.Now()
+	printed := false
+	show := func(scope string, list []Ban) {
+		for _, ban := range list {
+			if !ban.Active(now) {
+				continue
+			}
+			fmt.Printf("  • %s - %s - by %s %s\n", ban.Username, scope, ban.By, ban.Terms())
+			printed = true
+		}
+	}
+
+	show("all rooms", db.GlobalBans)
+	ids := make([]string, 0, len(db.Bans))
+	for id := range db.Bans {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		scope := "ad-hoc room " + id[:min(len(id), 8)]
+		if room, ok := db.Rooms[id]; ok {
+			scope = "room " + room.Name
+		}
+		show(scope, db.Bans[id])
+	}
+	if !printed {
+		fmt.Println("No bans in force.")
+	}
+	fmt.Println()
+	return nil
+}
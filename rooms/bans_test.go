@@ -0,0 +1,53 @@
+package rooms
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBanned(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	db := Database{
+		Bans: map[string][]Ban{
+			"ops": {
+				{Username: "bob", By: "alice"},
+				{Username: "carol", By: "alice", Until: now.Add(time.Hour)},
+				{Username: "dave", By: "alice", Until: now.Add(-time.Minute)},
+				{Username: "erin", By: "alice", Until: now},
+				{Username: "frank", By: "alice", Until: now.Add(time.Hour)},
+			},
+		},
+		GlobalBans: []Ban{
+			{Username: "mallory", By: "root"},
+			{Username: "carol", By: "root", Until: now.Add(24 * time.Hour)},
+			{Username: "trent", By: "root", Until: now.Add(-time.Hour)},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		room       string
+		username   string
+		wantBanned bool
+		wantBy     string
+	}{
+		{"permanent room ban", "ops", "bob", true, "alice"},
+		{"room ban stays in its room", "lobby", "bob", false, ""},
+		{"temporary room ban", "ops", "frank", true, "alice"},
+		{"expired room ban", "ops", "dave", false, ""},
+		{"ban ends at its deadline", "ops", "erin", false, ""},
+		{"global ban", "ops", "mallory", true, "root"},
+		{"global ban in every room", "lobby", "mallory", true, "root"},
+		{"global ban checked first", "ops", "carol", true, "root"},
+		{"expired global ban", "lobby", "trent", false, ""},
+		{"not banned", "ops", "alice", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ban, banned := db.Banned(tt.room, tt.username, now)
+			if banned != tt.wantBanned || ban.By != tt.wantBy {
+				t.Fatalf("Banned(%q, %q) = %v by %q, want %v by %q", tt.room, tt.username, banned, ban.By, tt.wantBanned, tt.wantBy)
+			}
+		})
+	}
+}
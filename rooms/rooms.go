@@ -0,0 +1,422 @@
+// Package rooms stores persistent room definitions: which room ID a name
+// refers to and who may join it.
+package rooms
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+	"github.com/EJ-Edwards/Secuchat-CLI/vault"
+)
+
+const RoomDBFile = "rooms.json"
+
+// roomDBLabel binds the sealed room database to its purpose.
+const roomDBLabel = "secuchat/rooms"
+
+// Room is a defined room. ID is e2e.RoomID of the PIN, which is what
+// clients present; the PIN itself is never stored. A room with no allowed
+// users or groups is open to every authenticated user.
+type Room struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	AllowedUsers  []string  `json:"allowed_users,omitempty"`
+	AllowedGroups []string  `json:"allowed_groups,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	CreatedBy     string    `json:"created_by"`
+
+	// Owner and Moderators hold the room-scoped roles. The owner grants
+	// and revokes moderator; someone stands in while the owner is away.
+	Owner      string   `json:"owner,omitempty"`
+	Moderators []string `json:"moderators,omitempty"`
+
+	// DefaultTTL, in seconds, makes every message in the room
+	// self-destruct; zero leaves messages in place.
+	DefaultTTL int `json:"default_ttl,omitempty"`
+
+	// Topic is a one-line summary and MOTD a multi-line message of the
+	// day, such as scope and rules of engagement, shown on every join.
+	Topic string `json:"topic,omitempty"`
+	MOTD  string `json:"motd,omitempty"`
+
+	// MaxFileSize caps file transfers in the room, in bytes, in place of
+	// the server default; negative disables them.
+	MaxFileSize int64 `json:"max_file_size,omitempty"`
+}
+
+type Database struct {
+	Rooms map[string]Room `json:"rooms"`
+
+	// Bans are kept by room ID, so ad-hoc rooms can have them too.
+	Bans       map[string][]Ban `json:"bans,omitempty"`
+	GlobalBans []Ban            `json:"global_bans,omitempty"`
+}
+
+// roomVault seals the room database at rest once set with UseVault.
+var roomVault *vault.Vault
+
+// UseVault makes Load decrypt and Save encrypt the room database.
+func UseVault(v *vault.Vault) {
+	roomVault = v
+}
+
+func Load() (Database, error) {
+	var db Database
+	db.Rooms = make(map[string]Room)
+	db.Bans = make(map[string][]Ban)
+
+	data, err := os.ReadFile(RoomDBFile)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return db, err
+	}
+
+	if vault.IsSealed(data) {
+		if roomVault == nil {
+			return db, fmt.Errorf("%s is encrypted; set %s or %s", RoomDBFile, vault.PassphraseEnv, vault.KeyFileEnv)
+		}
+		data, err = roomVault.Open(data, roomDBLabel)
+		if err != nil {
+			return db, fmt.Errorf("cannot decrypt %s: %w", RoomDBFile, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, &db); err != nil {
+		return db, fmt.Errorf("cannot parse %s: %w", RoomDBFile, err)
+	}
+	if db.Rooms == nil {
+		db.Rooms = make(map[string]Room)
+	}
+	if db.Bans == nil {
+		db.Bans = make(map[string][]Ban)
+	}
+	return db, nil
+}
+
+func Save(db Database) error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	if roomVault != nil {
+		data, err = roomVault.Seal(data, roomDBLabel)
+		if err != nil {
+			return err
+		}
+	}
+	return vault.WriteFileAtomic(RoomDBFile, data, 0600)
+}
+
+// dbMu serializes read-modify-write cycles on the room database within
+// this process.
+var dbMu sync.Mutex
+
+// Update loads the room database, applies update and saves it.
+func Update(update func(db *Database) error) error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	db, err := Load()
+	if err != nil {
+		return err
+	}
+	if err := update(&db); err != nil {
+		return err
+	}
+	return Save(db)
+}
+
+// isRoomID reports whether s has the shape of an e2e.RoomID.
+func isRoomID(s string) bool {
+	if len(s) != 32 {
+		return false
+	}
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// Lookup finds the room a client asked for. Encrypting clients send the
+// room ID; clients running without end-to-end encryption send the raw PIN,
+// which is mapped to its ID.
+func (db Database) Lookup(pin string) (Room, bool) {
+	if room, ok := db.Rooms[pin]; ok {
+		return room, true
+	}
+	if len(db.Rooms) == 0 || isRoomID(pin) {
+		return Room{}, false
+	}
+	room, ok := db.Rooms[e2e.RoomID(pin)]
+	return room, ok
+}
+
+// ByName finds a room by its name.
+func (db Database) ByName(name string) (Room, bool) {
+	for _, room := range db.Rooms {
+		if strings.EqualFold(room.Name, name) {
+			return room, true
+		}
+	}
+	return Room{}, false
+}
+
+// Allows reports whether a user with the given groups is on the room's
+// access list. Global admins may always join.
+func (r Room) Allows(username string, groups []string, isAdmin bool) bool {
+	if isAdmin || (len(r.AllowedUsers) == 0 && len(r.AllowedGroups) == 0) {
+		return true
+	}
+	for _, allowed := range r.AllowedUsers {
+		if allowed == username {
+			return true
+		}
+	}
+	for _, allowed := range r.AllowedGroups {
+		for _, group := range groups {
+			if allowed == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SplitList parses a comma-separated flag value, dropping empty entries.
+func SplitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// CreateRoom defines a room for pin under name.
+func CreateRoom(adminUsername string, isAdmin bool, name, pin string, users, groups []string) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can define rooms")
+	}
+	if name == "" || pin == "" {
+		return fmt.Errorf("room name and PIN are required")
+	}
+
+	id := e2e.RoomID(pin)
+	return Update(func(db *Database) error {
+		if _, exists := db.ByName(name); exists {
+			return fmt.Errorf("room '%s' already exists", name)
+		}
+		if existing, exists := db.Rooms[id]; exists {
+			return fmt.Errorf("that PIN is already used by room '%s'", existing.Name)
+		}
+		db.Rooms[id] = Room{
+			ID:            id,
+			Name:          name,
+			AllowedUsers:  users,
+			AllowedGroups: groups,
+			CreatedAt:     time.Now(),
+			CreatedBy:     adminUsername,
+			Owner:         adminUsername,
+		}
+		return nil
+	})
+}
+
+// SetAccess replaces the access list of the room called name.
+func SetAccess(adminUsername string, isAdmin bool, name string, users, groups []string) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can change room access")
+	}
+	return Update(func(db *Database) error {
+		room, exists := db.ByName(name)
+		if !exists {
+			return fmt.Errorf("room '%s' not found", name)
+		}
+		room.AllowedUsers = users
+		room.AllowedGroups = groups
+		db.Rooms[room.ID] = room
+		return nil
+	})
+}
+
+// SetRoles records the owner and moderators of room id. Rooms deleted in
+// the meantime are left alone.
+func SetRoles(id, owner string, moderators []string) error {
+	return Update(func(db *Database) error {
+		room, exists := db.Rooms[id]
+		if !exists {
+			return nil
+		}
+		room.Owner = owner
+		room.Moderators = moderators
+		db.Rooms[id] = room
+		return nil
+	})
+}
+
+// SetDefaultTTL records the self-destruct timer of room id in seconds.
+func SetDefaultTTL(id string, ttl int) error {
+	return Update(func(db *Database) error {
+		room, exists := db.Rooms[id]
+		if !exists {
+			return nil
+		}
+		room.DefaultTTL = ttl
+		db.Rooms[id] = room
+		return nil
+	})
+}
+
+// SetTopic records the topic of room id.
+func SetTopic(id, topic string) error {
+	return Update(func(db *Database) error {
+		room, exists := db.Rooms[id]
+		if !exists {
+			return nil
+		}
+		room.Topic = topic
+		db.Rooms[id] = room
+		return nil
+	})
+}
+
+// SetMOTD records the message of the day of room id.
+func SetMOTD(id, motd string) error {
+	return Update(func(db *Database) error {
+		room, exists := db.Rooms[id]
+		if !exists {
+			return nil
+		}
+		room.MOTD = motd
+		db.Rooms[id] = room
+		return nil
+	})
+}
+
+// SetRoomTopic sets the topic of the room called name.
+func SetRoomTopic(adminUsername string, isAdmin bool, name, topic string) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can change room settings")
+	}
+	return Update(func(db *Database) error {
+		room, exists := db.ByName(name)
+		if !exists {
+			return fmt.Errorf("room '%s' not found", name)
+		}
+		room.Topic = topic
+		db.Rooms[room.ID] = room
+		return nil
+	})
+}
+
+// SetRoomMOTD sets the message of the day of the room called name.
+func SetRoomMOTD(adminUsername string, isAdmin bool, name, motd string) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can change room settings")
+	}
+	return Update(func(db *Database) error {
+		room, exists := db.ByName(name)
+		if !exists {
+			return fmt.Errorf("room '%s' not found", name)
+		}
+		room.MOTD = motd
+		db.Rooms[room.ID] = room
+		return nil
+	})
+}
+
+// SetRoomTTL sets the self-destruct timer of the room called name.
+func SetRoomTTL(adminUsername string, isAdmin bool, name string, ttl int) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can change room settings")
+	}
+	return Update(func(db *Database) error {
+		room, exists := db.ByName(name)
+		if !exists {
+			return fmt.Errorf("room '%s' not found", name)
+		}
+		room.DefaultTTL = ttl
+		db.Rooms[room.ID] = room
+		return nil
+	})
+}
+
+// SetRoomFileCap sets the file transfer cap of the room called name; zero
+// restores the server default.
+func SetRoomFileCap(adminUsername string, isAdmin bool, name string, size int64) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can change room settings")
+	}
+	return Update(func(db *Database) error {
+		room, exists := db.ByName(name)
+		if !exists {
+			return fmt.Errorf("room '%s' not found", name)
+		}
+		room.MaxFileSize = size
+		db.Rooms[room.ID] = room
+		return nil
+	})
+}
+
+// DeleteRoom removes the definition of the room called name.
+func DeleteRoom(adminUsername string, isAdmin bool, name string) error {
+	if !isAdmin {
+		return fmt.Errorf("only admins can delete rooms")
+	}
+	return Update(func(db *Database) error {
+		room, exists := db.ByName(name)
+		if !exists {
+			return fmt.Errorf("room '%s' not found", name)
+		}
+		delete(db.Rooms, room.ID)
+		delete(db.Bans, room.ID)
+		return nil
+	})
+}
+
+// ListRooms prints the defined rooms and their access lists.
+func ListRooms() error {
+	db, err := Load()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🚪 Defined Rooms")
+	fmt.Println("================")
+	if len(db.Rooms) == 0 {
+		fmt.Println("No rooms defined.")
+		return nil
+	}
+
+	list := make([]Room, 0, len(db.Rooms))
+	for _, room := range db.Rooms {
+		list = append(list, room)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	for _, room := range list {
+		access := "any authenticated user"
+		if len(room.AllowedUsers) > 0 || len(room.AllowedGroups) > 0 {
+			access = fmt.Sprintf("users [%s] groups [%s]",
+				strings.Join(room.AllowedUsers, ", "), strings.Join(room.AllowedGroups, ", "))
+		}
+		fmt.Printf("  • %s - Created: %s by %s - Owner: %s - %s\n",
+			room.Name, room.CreatedAt.Format("2006-01-02"), room.CreatedBy, room.Owner, access)
+		if room.Topic != "" {
+			fmt.Printf("    Topic: %s\n", room.Topic)
+		}
+		if room.MaxFileSize < 0 {
+			fmt.Println("    File transfer: disabled")
+		} else if room.MaxFileSize > 0 {
+			fmt.Printf("    File transfer: up to %d bytes\n", room.MaxFileSize)
+		}
+	}
+	fmt.Println()
+	return nil
+}
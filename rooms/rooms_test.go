@@ -0,0 +1,61 @@
+package rooms
+
+import (
+	"testing"
+
+	"github.com/EJ-Edwards/Secuchat-CLI/e2e"
+)
+
+func TestRoomAllows(t *testing.T) {
+	tests := []struct {
+		name     string
+		room     Room
+		username string
+		groups   []string
+		isAdmin  bool
+		want     bool
+	}{
+		{"open room", Room{}, "alice", nil, false, true},
+		{"listed user", Room{AllowedUsers: []string{"bob", "alice"}}, "alice", nil, false, true},
+		{"unlisted user", Room{AllowedUsers: []string{"bob"}}, "alice", nil, false, false},
+		{"listed group", Room{AllowedGroups: []string{"red"}}, "alice", []string{"blue", "red"}, false, true},
+		{"other group", Room{AllowedGroups: []string{"red"}}, "alice", []string{"blue"}, false, false},
+		{"no groups", Room{AllowedGroups: []string{"red"}}, "alice", nil, false, false},
+		{"group list ignores users", Room{AllowedUsers: []string{"bob"}, AllowedGroups: []string{"red"}}, "alice", []string{"red"}, false, true},
+		{"admin override", Room{AllowedUsers: []string{"bob"}}, "alice", nil, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.room.Allows(tt.username, tt.groups, tt.isAdmin); got != tt.want {
+				t.Fatalf("Allows(%q, %v, %v) = %v, want %v", tt.username, tt.groups, tt.isAdmin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ops := Room{ID: e2e.RoomID("1234"), Name: "ops"}
+	db := Database{Rooms: map[string]Room{ops.ID: ops}}
+
+	tests := []struct {
+		name string
+		db   Database
+		pin  string
+		want string
+		ok   bool
+	}{
+		{"room ID", db, ops.ID, "ops", true},
+		{"raw PIN", db, "1234", "ops", true},
+		{"unknown PIN", db, "9999", "", false},
+		{"unknown room ID", db, e2e.RoomID("9999"), "", false},
+		{"no rooms defined", Database{}, "1234", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room, ok := tt.db.Lookup(tt.pin)
+			if ok != tt.ok || room.Name != tt.want {
+				t.Fatalf("Lookup(%q) = %q, %v, want %q, %v", tt.pin, room.Name, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package tlsutil generates self-signed server certificates for isolated
+// networks and builds client TLS configs pinned to a certificate fingerprint.
+package tlsutil
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Fingerprint returns the SHA-256 of a DER certificate as colon-separated hex.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// normalizeFingerprint accepts fingerprints with or without colons, in any case.
+func normalizeFingerprint(fingerprint string) ([]byte, error) {
+	clean := strings.NewReplacer(":", "", " ", "").Replace(fingerprint)
+	clean = strings.TrimPrefix(strings.ToLower(clean), "sha256")
+	sum, err := hex.DecodeString(clean)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("fingerprint must be a SHA-256 hex digest")
+	}
+	return sum, nil
+}
+
+// GenerateSelfSigned writes a new ECDSA P-256 certificate and key valid for
+// hosts (DNS names or IP addresses). Existing files are never overwritten.
+// It returns the certificate fingerprint for clients to pin.
+func GenerateSelfSigned(certPath, keyPath string, hosts []string, validFor time.Duration) (string, error) {
+	for _, path := range []string{certPath, keyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return "", fmt.Errorf("%s already exists", path)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Secuchat-CLI"}, CommonName: "secuchat-server"},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return "", err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writePEM(keyPath, "PRIVATE KEY", keyDER, 0600); err != nil {
+		return "", err
+	}
+	if err := writePEM(certPath, "CERTIFICATE", der, 0644); err != nil {
+		return "", err
+	}
+
+	return Fingerprint(der), nil
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CertFileFingerprint reads the first certificate in a PEM file and returns
+// its fingerprint.
+func CertFileFingerprint(certPath string) (string, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("%s: no PEM certificate found", certPath)
+	}
+	return Fingerprint(block.Bytes), nil
+}
+
+// PinnedConfig returns a client TLS config that accepts exactly the server
+// certificate with the given SHA-256 fingerprint, self-signed or not.
+func PinnedConfig(fingerprint string) (*tls.Config, error) {
+	want, err := normalizeFingerprint(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Chain verification is replaced by the pin check below.
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("server presented no certificate")
+			}
+			got := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(got[:], want) {
+				return fmt.Errorf("server certificate fingerprint %s does not match pinned fingerprint", Fingerprint(rawCerts[0]))
+			}
+			return nil
+		},
+	}, nil
+}
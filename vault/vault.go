@@ -0,0 +1,186 @@
+// Package vault seals server-side files at rest with XChaCha20-Poly1305 under
+// a key derived (argon2id) from a master passphrase or key file.
+package vault
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// Format tags sealed files so they can be told apart from plaintext JSON.
+	Format = "secuchat-sealed-v1"
+
+	PassphraseEnv = "SECUCHAT_DB_PASSPHRASE"
+	KeyFileEnv    = "SECUCHAT_DB_KEY_FILE"
+
+	saltSize = 16
+)
+
+// envelope is the on-disk form of a sealed file.
+type envelope struct {
+	Format string `json:"format"`
+	KDF    string `json:"kdf"`
+	Salt   []byte `json:"salt"`
+	Nonce  []byte `json:"nonce"`
+	Data   []byte `json:"data"`
+}
+
+// Vault holds the master secret and caches derived keys by salt, so only the
+// first seal or open of a file pays for argon2.
+type Vault struct {
+	secret []byte
+
+	mu       sync.Mutex
+	keys     map[string]cipher.AEAD
+	sealSalt []byte
+}
+
+func New(secret []byte) (*Vault, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("empty master secret")
+	}
+	return &Vault{secret: secret, keys: make(map[string]cipher.AEAD)}, nil
+}
+
+// SecretFromEnv returns the master secret from SECUCHAT_DB_KEY_FILE or
+// SECUCHAT_DB_PASSPHRASE, or nil when neither is set.
+func SecretFromEnv() ([]byte, error) {
+	if path := os.Getenv(KeyFileEnv); path != "" {
+		return ReadKeyFile(path)
+	}
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	return nil, nil
+}
+
+// ReadKeyFile reads a master secret from a file, ignoring surrounding whitespace.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret := bytes.TrimSpace(data)
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("%s: key file must hold at least 16 bytes", path)
+	}
+	return secret, nil
+}
+
+func (v *Vault) aead(salt []byte) (cipher.AEAD, error) {
+	if aead, ok := v.keys[string(salt)]; ok {
+		return aead, nil
+	}
+	key := argon2.IDKey(v.secret, salt, 3, 64*1024, 4, chacha20poly1305.KeySize)
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	v.keys[string(salt)] = aead
+	return aead, nil
+}
+
+// Seal encrypts plaintext. label names the file's purpose and must be given
+// again to Open, so a sealed file cannot be swapped in for another.
+func (v *Vault) Seal(plaintext []byte, label string) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.sealSalt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		v.sealSalt = salt
+	}
+	aead, err := v.aead(v.sealSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(envelope{
+		Format: Format,
+		KDF:    "argon2id",
+		Salt:   v.sealSalt,
+		Nonce:  nonce,
+		Data:   aead.Seal(nil, nonce, plaintext, []byte(label)),
+	}, "", "  ")
+}
+
+// Open decrypts a file produced by Seal with the same label.
+func (v *Vault) Open(data []byte, label string) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Format != Format {
+		return nil, fmt.Errorf("not a sealed file")
+	}
+	if len(env.Salt) != saltSize {
+		return nil, fmt.Errorf("sealed file has an invalid salt")
+	}
+
+	v.mu.Lock()
+	aead, err := v.aead(env.Salt)
+	v.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("sealed file has an invalid nonce")
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Data, []byte(label))
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or key file, or the file was modified")
+	}
+	return plaintext, nil
+}
+
+// IsSealed reports whether data looks like the output of Seal.
+func IsSealed(data []byte) bool {
+	var probe struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(data, &probe) == nil && strings.EqualFold(probe.Format, Format)
+}
+
+// WriteFileAtomic replaces path with data via a temporary file and rename, so
+// a crash mid-write never leaves a truncated file behind.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	v, err := New([]byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong, err := New([]byte("incorrect horse battery staple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte(`{"users":{}}`)
+	sealed, err := v.Seal(plaintext, "secuchat/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(sealed, []byte(`"data": "`), []byte(`"data": "A`), 1)
+
+	tests := []struct {
+		name    string
+		vault   *Vault
+		data    []byte
+		label   string
+		wantErr bool
+	}{
+		{"same key and label", v, sealed, "secuchat/users", false},
+		{"wrong label", v, sealed, "secuchat/rooms", true},
+		{"wrong key", wrong, sealed, "secuchat/users", true},
+		{"tampered", v, tampered, "secuchat/users", true},
+		{"plaintext", v, plaintext, "secuchat/users", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.vault.Open(tt.data, tt.label)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Open() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("Open() = %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
+
+func TestIsSealed(t *testing.T) {
+	v, err := New([]byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := v.Seal([]byte("{}"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"sealed", sealed, true},
+		{"plaintext", []byte(`{"users":{}}`), false},
+		{"not json", []byte("hello"), false},
+		{"empty", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSealed(tt.data); got != tt.want {
+				t.Fatalf("IsSealed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}